@@ -5,22 +5,29 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/123jjck/avito-trainee-assignment/internal/buildinfo"
 	"github.com/123jjck/avito-trainee-assignment/internal/db"
 	"github.com/123jjck/avito-trainee-assignment/internal/service"
 	"github.com/123jjck/avito-trainee-assignment/internal/transport/httpserver"
+	"github.com/123jjck/avito-trainee-assignment/internal/webhook"
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	dsn := getenv("DATABASE_URL", "postgres://pr_service:pr_service@db:5432/pr_service?sslmode=disable")
 
-	sqlDB, err := db.Open(dsn)
+	sqlDB, err := db.Open(dsn, dbOptionsFromEnv())
 	if err != nil {
 		log.Fatalf("db open: %v", err)
 	}
-	defer sqlDB.Close()
 
 	if err := waitForDB(ctx, sqlDB); err != nil {
 		log.Fatalf("db ping failed: %v", err)
@@ -30,18 +37,121 @@ func main() {
 	}
 
 	svc := service.New(sqlDB)
+	minApprovals, err := strconv.Atoi(getenv("MIN_APPROVALS", "0"))
+	if err != nil || minApprovals < 0 {
+		log.Fatalf("invalid MIN_APPROVALS: %q", os.Getenv("MIN_APPROVALS"))
+	}
+	svc.SetMinApprovals(minApprovals)
+	requireReviewer, err := strconv.ParseBool(getenv("REQUIRE_REVIEWER", "false"))
+	if err != nil {
+		log.Fatalf("invalid REQUIRE_REVIEWER: %q", os.Getenv("REQUIRE_REVIEWER"))
+	}
+	svc.SetRequireReviewer(requireReviewer)
+	requireActiveAuthor, err := strconv.ParseBool(getenv("REQUIRE_ACTIVE_AUTHOR", "true"))
+	if err != nil {
+		log.Fatalf("invalid REQUIRE_ACTIVE_AUTHOR: %q", os.Getenv("REQUIRE_ACTIVE_AUTHOR"))
+	}
+	svc.SetRequireActiveAuthor(requireActiveAuthor)
+	maxOpenReviews, err := strconv.Atoi(getenv("MAX_OPEN_REVIEWS", "0"))
+	if err != nil || maxOpenReviews < 0 {
+		log.Fatalf("invalid MAX_OPEN_REVIEWS: %q", os.Getenv("MAX_OPEN_REVIEWS"))
+	}
+	svc.SetMaxOpenReviews(maxOpenReviews)
+	opTimeout, err := time.ParseDuration(getenv("DB_OP_TIMEOUT", "5s"))
+	if err != nil || opTimeout < 0 {
+		log.Fatalf("invalid DB_OP_TIMEOUT: %q", os.Getenv("DB_OP_TIMEOUT"))
+	}
+	svc.SetOpTimeout(opTimeout)
+	sampleSize, err := strconv.Atoi(getenv("CANDIDATE_SAMPLE_SIZE", "0"))
+	if err != nil || sampleSize < 0 {
+		log.Fatalf("invalid CANDIDATE_SAMPLE_SIZE: %q", os.Getenv("CANDIDATE_SAMPLE_SIZE"))
+	}
+	svc.SetSampleInDB(sampleSize)
+	switch strategy := service.Strategy(getenv("ASSIGNMENT_STRATEGY", string(service.StrategyRandom))); strategy {
+	case service.StrategyRandom, service.StrategyRoundRobin:
+		svc.SetStrategy(strategy)
+	default:
+		log.Fatalf("invalid ASSIGNMENT_STRATEGY: %q", strategy)
+	}
 	server := httpserver.New(svc)
+	server.SetSchemaVersion(func(ctx context.Context) (int, error) {
+		return db.SchemaVersion(ctx, sqlDB)
+	}, db.LatestVersion())
+	if pattern := os.Getenv("ID_PATTERN"); pattern != "" {
+		if err := server.SetIDPattern(pattern); err != nil {
+			log.Fatalf("invalid ID_PATTERN: %v", err)
+		}
+	}
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid MAX_BODY_BYTES: %q", v)
+		}
+		server.SetMaxBodyBytes(n)
+	}
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		server.SetCORSOrigins(strings.Split(origins, ","))
+	}
+
+	logRequests, err := strconv.ParseBool(getenv("LOG_REQUESTS", "false"))
+	if err != nil {
+		log.Fatalf("invalid LOG_REQUESTS: %q", os.Getenv("LOG_REQUESTS"))
+	}
+	server.SetRequestLogging(logRequests)
+
+	requestTimeout, err := time.ParseDuration(getenv("REQUEST_TIMEOUT", "5s"))
+	if err != nil || requestTimeout < 0 {
+		log.Fatalf("invalid REQUEST_TIMEOUT: %q", os.Getenv("REQUEST_TIMEOUT"))
+	}
+	server.SetRequestTimeout(requestTimeout)
+
+	shutdownTimeout, err := time.ParseDuration(getenv("SHUTDOWN_TIMEOUT", "10s"))
+	if err != nil || shutdownTimeout <= 0 {
+		log.Fatalf("invalid SHUTDOWN_TIMEOUT: %q", os.Getenv("SHUTDOWN_TIMEOUT"))
+	}
 
 	port := getenv("PORT", "8080")
 	addr := ":" + port
-	log.Printf("starting server on %s", addr)
-	httpServer := &http.Server{
-		Addr:              addr,
-		Handler:           server.Handler(),
-		ReadHeaderTimeout: 5 * time.Second,
+	info := buildinfo.Get()
+	log.Printf("starting server %s (commit %s, built %s, %s) on %s", info.Version, info.Commit, info.BuildTime, info.GoVersion, addr)
+	httpServer := newHTTPServer(addr, server.Handler())
+	dispatcherDone := make(chan struct{})
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		log.Printf("delivering events to webhook %s", webhookURL)
+		go func() {
+			defer close(dispatcherDone)
+			webhook.New(sqlDB, webhookURL).Run(ctx)
+		}()
+	} else {
+		close(dispatcherDone)
 	}
-	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server stopped: %v", err)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server stopped: %v", err)
+		}
+	case <-ctx.Done():
+		stop() // a second signal terminates immediately
+		log.Printf("shutdown signal received, draining connections (timeout %s)", shutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("server shutdown: %v", err)
+		} else {
+			log.Printf("http server stopped")
+		}
+	}
+	stop()
+	<-dispatcherDone
+	log.Printf("closing database connections")
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("db close: %v", err)
 	}
 }
 
@@ -52,6 +162,53 @@ func getenv(key, def string) string {
 	return def
 }
 
+// newHTTPServer bounds how long a client may take to send a request and read
+// the response, so slow connections cannot be held open indefinitely.
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       envPositiveDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      envPositiveDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       envPositiveDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+	}
+}
+
+func dbOptionsFromEnv() db.Options {
+	opts := db.DefaultOptions()
+	opts.MaxOpenConns = envPositiveInt("DB_MAX_OPEN", opts.MaxOpenConns)
+	opts.MaxIdleConns = envPositiveInt("DB_MAX_IDLE", opts.MaxIdleConns)
+	opts.ConnMaxLifetime = envPositiveDuration("DB_CONN_MAX_LIFETIME", opts.ConnMaxLifetime)
+	return opts
+}
+
+func envPositiveInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("ignoring invalid %s=%q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+func envPositiveDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("ignoring invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
 func waitForDB(ctx context.Context, dbConn interface{ PingContext(context.Context) error }) error {
 	var lastErr error
 	for i := 0; i < 10; i++ {
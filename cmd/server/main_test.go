@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/db"
+)
+
+func TestDBOptionsFromEnvDefaults(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN", "")
+	t.Setenv("DB_MAX_IDLE", "")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "")
+
+	if got, want := dbOptionsFromEnv(), db.DefaultOptions(); got != want {
+		t.Fatalf("options = %+v, want defaults %+v", got, want)
+	}
+}
+
+func TestDBOptionsFromEnvOverrides(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN", "40")
+	t.Setenv("DB_MAX_IDLE", "20")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "10m")
+
+	want := db.Options{MaxOpenConns: 40, MaxIdleConns: 20, ConnMaxLifetime: 10 * time.Minute}
+	if got := dbOptionsFromEnv(); got != want {
+		t.Fatalf("options = %+v, want %+v", got, want)
+	}
+}
+
+func TestEnvPositiveIntFallsBack(t *testing.T) {
+	for _, v := range []string{"abc", "0", "-3", "1.5"} {
+		t.Setenv("TEST_POSITIVE_INT", v)
+		if got := envPositiveInt("TEST_POSITIVE_INT", 7); got != 7 {
+			t.Errorf("envPositiveInt(%q) = %d, want default 7", v, got)
+		}
+	}
+}
+
+func TestEnvPositiveDurationFallsBack(t *testing.T) {
+	for _, v := range []string{"soon", "0s", "-1m", "10"} {
+		t.Setenv("TEST_POSITIVE_DURATION", v)
+		if got := envPositiveDuration("TEST_POSITIVE_DURATION", time.Hour); got != time.Hour {
+			t.Errorf("envPositiveDuration(%q) = %s, want default 1h", v, got)
+		}
+	}
+}
+
+func TestNewHTTPServerDefaults(t *testing.T) {
+	t.Setenv("HTTP_READ_TIMEOUT", "")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "")
+	handler := http.NewServeMux()
+
+	srv := newHTTPServer(":8080", handler)
+	if srv.Addr != ":8080" || srv.Handler != handler {
+		t.Fatalf("addr = %q, handler = %v", srv.Addr, srv.Handler)
+	}
+	if srv.ReadHeaderTimeout != 5*time.Second || srv.ReadTimeout != 15*time.Second ||
+		srv.WriteTimeout != 30*time.Second || srv.IdleTimeout != 60*time.Second {
+		t.Fatalf("timeouts = header %s, read %s, write %s, idle %s",
+			srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+func TestNewHTTPServerEnvOverrides(t *testing.T) {
+	t.Setenv("HTTP_READ_TIMEOUT", "2s")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "1m")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "bogus")
+
+	srv := newHTTPServer(":8080", http.NewServeMux())
+	if srv.ReadTimeout != 2*time.Second || srv.WriteTimeout != time.Minute {
+		t.Fatalf("read %s, write %s, want 2s and 1m", srv.ReadTimeout, srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != 60*time.Second {
+		t.Fatalf("idle = %s, want the default for an invalid value", srv.IdleTimeout)
+	}
+}
@@ -0,0 +1,23 @@
+// Package buildinfo holds build metadata injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/123jjck/avito-trainee-assignment/internal/buildinfo.Version=v1.2.0"
+package buildinfo
+
+import "runtime"
+
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+}
@@ -0,0 +1,24 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetDefaults(t *testing.T) {
+	want := Info{Version: "dev", Commit: "unknown", BuildTime: "unknown", GoVersion: runtime.Version()}
+	if got := Get(); got != want {
+		t.Fatalf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetReflectsLinkedValues(t *testing.T) {
+	defer func(v, c, b string) { Version, Commit, BuildTime = v, c, b }(Version, Commit, BuildTime)
+	// what -ldflags -X sets at link time
+	Version, Commit, BuildTime = "v1.2.0", "abc1234", "2025-05-01T10:00:00Z"
+
+	got := Get()
+	if got.Version != "v1.2.0" || got.Commit != "abc1234" || got.BuildTime != "2025-05-01T10:00:00Z" {
+		t.Fatalf("Get() = %+v", got)
+	}
+}
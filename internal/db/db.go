@@ -9,49 +9,303 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func Open(dsn string) (*sql.DB, error) {
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: time.Hour,
+	}
+}
+
+func Open(dsn string, opts Options) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
-	db.SetMaxIdleConns(5)
-	db.SetMaxOpenConns(10)
-	db.SetConnMaxLifetime(time.Hour)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
 	return db, nil
 }
 
+const migrationLockID = 7262001
+
+type migration struct {
+	version int
+	stmts   []string
+}
+
+// migrations are applied in order and recorded in schema_migrations; only
+// versions above the current maximum run, so append new versions instead of
+// editing applied ones.
+var migrations = []migration{
+	{
+		version: 1,
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS teams (
+				team_name TEXT PRIMARY KEY
+			);`,
+			`CREATE TABLE IF NOT EXISTS users (
+				user_id TEXT PRIMARY KEY,
+				username TEXT NOT NULL,
+				team_name TEXT NOT NULL REFERENCES teams(team_name),
+				is_active BOOLEAN NOT NULL,
+				max_open_reviews INT NULL,
+				unavailable_until TIMESTAMPTZ NULL
+			);`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_open_reviews INT NULL;`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS unavailable_until TIMESTAMPTZ NULL;`,
+			`CREATE TABLE IF NOT EXISTS pull_requests (
+				pull_request_id TEXT PRIMARY KEY,
+				pull_request_name TEXT NOT NULL,
+				author_id TEXT NOT NULL REFERENCES users(user_id),
+				status TEXT NOT NULL CHECK (status IN ('OPEN', 'MERGED', 'CLOSED')),
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				merged_at TIMESTAMPTZ NULL,
+				closed_at TIMESTAMPTZ NULL
+			);`,
+			`ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ NULL;`,
+			`ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_status_check;`,
+			`ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_status_check CHECK (status IN ('OPEN', 'MERGED', 'CLOSED'));`,
+			`CREATE TABLE IF NOT EXISTS pr_reviewers (
+				pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL REFERENCES users(user_id),
+				PRIMARY KEY (pull_request_id, user_id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS pr_approvals (
+				pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL REFERENCES users(user_id),
+				approved_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				PRIMARY KEY (pull_request_id, user_id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS team_links (
+				team_a TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+				team_b TEXT NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+				PRIMARY KEY (team_a, team_b),
+				CHECK (team_a < team_b)
+			);`,
+			`CREATE TABLE IF NOT EXISTS reassignment_log (
+				id BIGSERIAL PRIMARY KEY,
+				pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+				old_user_id TEXT NOT NULL REFERENCES users(user_id),
+				new_user_id TEXT NOT NULL REFERENCES users(user_id),
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_reassignment_log_pr ON reassignment_log(pull_request_id);`,
+			`CREATE TABLE IF NOT EXISTS events (
+				id BIGSERIAL PRIMARY KEY,
+				event_type TEXT NOT NULL,
+				payload JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_name);`,
+			`CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user ON pr_reviewers(user_id);`,
+		},
+	},
+	{
+		version: 2,
+		stmts: []string{
+			`ALTER TABLE events ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ NULL;`,
+			`CREATE INDEX IF NOT EXISTS idx_events_undelivered ON events(id) WHERE delivered_at IS NULL;`,
+		},
+	},
+	{
+		version: 3,
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS team_assignment_cursor (
+				team_name TEXT PRIMARY KEY REFERENCES teams(team_name) ON DELETE CASCADE,
+				last_user_id TEXT NOT NULL
+			);`,
+		},
+	},
+	{
+		version: 4,
+		stmts: []string{
+			`CREATE UNIQUE INDEX IF NOT EXISTS users_team_username_key ON users(team_name, username);`,
+		},
+	},
+	{
+		version: 5,
+		stmts: []string{
+			`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;`,
+			`ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+				FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE;`,
+			`ALTER TABLE team_assignment_cursor DROP CONSTRAINT IF EXISTS team_assignment_cursor_team_name_fkey;`,
+			`ALTER TABLE team_assignment_cursor ADD CONSTRAINT team_assignment_cursor_team_name_fkey
+				FOREIGN KEY (team_name) REFERENCES teams(team_name) ON DELETE CASCADE ON UPDATE CASCADE;`,
+		},
+	},
+	{
+		version: 6,
+		stmts: []string{
+			`ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMPTZ NOT NULL DEFAULT now();`,
+		},
+	},
+	{
+		version: 7,
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id BIGSERIAL PRIMARY KEY,
+				operation TEXT NOT NULL,
+				entity_ids TEXT[] NOT NULL,
+				payload JSONB NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_entity_ids ON audit_log USING GIN (entity_ids);`,
+		},
+	},
+	{
+		version: 8,
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS pr_change_requests (
+				pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL REFERENCES users(user_id),
+				requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+				PRIMARY KEY (pull_request_id, user_id)
+			);`,
+		},
+	},
+	{
+		version: 9,
+		stmts: []string{
+			`ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();`,
+			`UPDATE pull_requests SET updated_at = GREATEST(created_at, merged_at, closed_at);`,
+		},
+	},
+	{
+		version: 10,
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS pr_reviewer_history (
+				id BIGSERIAL PRIMARY KEY,
+				pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL REFERENCES users(user_id),
+				action TEXT NOT NULL CHECK (action IN ('ASSIGNED', 'REMOVED', 'REPLACED')),
+				at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_pr_reviewer_history_pr ON pr_reviewer_history(pull_request_id, id);`,
+		},
+	},
+	{
+		version: 11,
+		stmts: []string{
+			`ALTER TABLE teams ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT false;`,
+		},
+	},
+	{
+		version: 12,
+		stmts: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT;`,
+		},
+	},
+	{
+		version: 13,
+		stmts: []string{
+			`ALTER TABLE reassignment_log
+				ADD COLUMN IF NOT EXISTS actor_id TEXT REFERENCES users(user_id) ON DELETE SET NULL;`,
+		},
+	},
+	{
+		version: 14,
+		stmts: []string{
+			`ALTER TABLE teams
+				ADD COLUMN IF NOT EXISTS default_reviewer_count INT NOT NULL DEFAULT 2
+				CHECK (default_reviewer_count > 0);`,
+		},
+	},
+}
+
 func RunMigrations(ctx context.Context, db *sql.DB) error {
-	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS teams (
-			team_name TEXT PRIMARY KEY
-		);`,
-		`CREATE TABLE IF NOT EXISTS users (
-			user_id TEXT PRIMARY KEY,
-			username TEXT NOT NULL,
-			team_name TEXT NOT NULL REFERENCES teams(team_name),
-			is_active BOOLEAN NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS pull_requests (
-			pull_request_id TEXT PRIMARY KEY,
-			pull_request_name TEXT NOT NULL,
-			author_id TEXT NOT NULL REFERENCES users(user_id),
-			status TEXT NOT NULL CHECK (status IN ('OPEN', 'MERGED')),
-			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
-			merged_at TIMESTAMPTZ NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS pr_reviewers (
-			pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
-			user_id TEXT NOT NULL REFERENCES users(user_id),
-			PRIMARY KEY (pull_request_id, user_id)
-		);`,
-		`CREATE INDEX IF NOT EXISTS idx_users_team ON users(team_name);`,
-		`CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user ON pr_reviewers(user_id);`,
+	if err := createMigrationsTable(ctx, db); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
 	}
 
-	for _, stmt := range stmts {
-		if _, err := db.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("apply migration: %w", err)
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("apply migration %d: %w", m.version, err)
 		}
 	}
 	return nil
 }
+
+// LatestVersion is the schema version RunMigrations brings the database to.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the highest migration applied to the database.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var v int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("load schema version: %w", err)
+	}
+	return v, nil
+}
+
+// createMigrationsTable holds the migration lock as well: concurrent
+// CREATE TABLE IF NOT EXISTS statements can still collide in PostgreSQL.
+func createMigrationsTable(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);`,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serializes concurrent instances booting against the same database; the
+	// loser re-checks the version once the lock is released.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	var done bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version,
+	).Scan(&done); err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	for _, stmt := range m.stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,104 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/db"
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+)
+
+func appliedMigrations(t *testing.T, conn *sql.DB) map[int]time.Time {
+	t.Helper()
+	rows, err := conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		t.Fatalf("load schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var v int
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			t.Fatalf("scan schema_migrations: %v", err)
+		}
+		applied[v] = at
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("load schema_migrations: %v", err)
+	}
+	return applied
+}
+
+func TestRunMigrationsOnlyAppliesNewVersions(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.OpenEmpty(t)
+
+	if err := db.RunMigrations(ctx, conn); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	first := appliedMigrations(t, conn)
+	if len(first) != db.LatestVersion() {
+		t.Fatalf("applied %d migrations, want %d", len(first), db.LatestVersion())
+	}
+
+	if err := db.RunMigrations(ctx, conn); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	second := appliedMigrations(t, conn)
+	for v, at := range first {
+		if !second[v].Equal(at) {
+			t.Fatalf("migration %d re-applied on the second run", v)
+		}
+	}
+
+	// forgetting the latest version makes it new again: only it may run
+	latest := db.LatestVersion()
+	if _, err := conn.Exec(`DELETE FROM schema_migrations WHERE version = $1`, latest); err != nil {
+		t.Fatalf("forget latest migration: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := db.RunMigrations(ctx, conn); err != nil {
+		t.Fatalf("third run: %v", err)
+	}
+	third := appliedMigrations(t, conn)
+	for v, at := range first {
+		if v == latest {
+			if !third[v].After(at) {
+				t.Fatalf("migration %d was not applied again", v)
+			}
+			continue
+		}
+		if !third[v].Equal(at) {
+			t.Fatalf("migration %d re-applied with only %d pending", v, latest)
+		}
+	}
+
+	got, err := db.SchemaVersion(ctx, conn)
+	if err != nil {
+		t.Fatalf("schema version: %v", err)
+	}
+	if got != latest {
+		t.Fatalf("schema version = %d, want %d", got, latest)
+	}
+}
+
+func TestRunMigrationsConcurrently(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.OpenEmpty(t)
+
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- db.RunMigrations(ctx, conn) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("concurrent run: %v", err)
+		}
+	}
+	if applied := appliedMigrations(t, conn); len(applied) != db.LatestVersion() {
+		t.Fatalf("applied %d migrations, want each of %d once", len(applied), db.LatestVersion())
+	}
+}
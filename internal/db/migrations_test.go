@@ -0,0 +1,17 @@
+package db
+
+import "testing"
+
+func TestMigrationVersionsIncrease(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %d has version %d, want %d", i, m.version, i+1)
+		}
+		if len(m.stmts) == 0 {
+			t.Fatalf("migration %d has no statements", m.version)
+		}
+	}
+	if LatestVersion() != len(migrations) {
+		t.Fatalf("LatestVersion = %d, want %d", LatestVersion(), len(migrations))
+	}
+}
@@ -0,0 +1,84 @@
+// Package dbtest gives tests a throwaway PostgreSQL schema. Tests that need a
+// database are skipped unless TEST_DATABASE_URL points at one.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/db"
+)
+
+// Open returns a connection to a fresh schema with all migrations applied.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	conn := OpenEmpty(t)
+	if err := db.RunMigrations(context.Background(), conn); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return conn
+}
+
+// OpenEmpty returns a connection to a fresh schema without any tables. The
+// schema is dropped when the test finishes.
+func OpenEmpty(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open admin connection: %v", err)
+	}
+	schema := "test_" + randomSuffix(t)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+
+	conn, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("open test connection: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+	return conn
+}
+
+// withSearchPath makes every connection of the pool resolve tables in schema;
+// lib/pq passes unknown DSN keys to the server as run-time parameters.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+func randomSuffix(t testing.TB) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("random schema name: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,43 +1,189 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 const (
 	StatusOpen   = "OPEN"
 	StatusMerged = "MERGED"
+	StatusClosed = "CLOSED"
 )
 
 type TeamMember struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	IsActive bool   `json:"is_active"`
+	UserID         string  `json:"user_id"`
+	Username       string  `json:"username"`
+	IsActive       bool    `json:"is_active"`
+	MaxOpenReviews *int    `json:"max_open_reviews,omitempty"`
+	Role           *string `json:"role,omitempty"`
+}
+
+// MemberList decodes either an array of members or an object keyed by
+// user_id, e.g. {"u1": {"username": "Alice", "is_active": true}}; the object
+// form keeps the order of its keys.
+type MemberList []TeamMember
+
+func (l *MemberList) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		var members []TeamMember
+		if err := dec.Decode(&members); err != nil {
+			return withMembersPath(err, "")
+		}
+		*l = members
+		return nil
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	members := MemberList{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		userID := tok.(string)
+		var m TeamMember
+		if err := dec.Decode(&m); err != nil {
+			return withMembersPath(err, userID)
+		}
+		if m.UserID != "" && m.UserID != userID {
+			return fmt.Errorf("member %s has mismatched user_id %s", userID, m.UserID)
+		}
+		m.UserID = userID
+		members = append(members, m)
+	}
+	*l = members
+	return nil
+}
+
+// withMembersPath roots the field of a type error at the team's "members"
+// key: the outer decoder adds no path to errors returned by UnmarshalJSON, so
+// clients would otherwise see "0.user_id" or a bare "user_id".
+func withMembersPath(err error, userID string) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	path := []string{"members"}
+	if userID != "" {
+		path = append(path, userID)
+	}
+	if typeErr.Field != "" {
+		path = append(path, typeErr.Field)
+	}
+	typeErr.Field = strings.Join(path, ".")
+	return err
 }
 
 type Team struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName string     `json:"team_name"`
+	Members  MemberList `json:"members"`
+	// MemberCount is len(Members) as returned, so it shrinks when inactive
+	// members are filtered out.
+	MemberCount int `json:"member_count"`
+	// ActiveCount and TotalCount always cover the whole team, whatever was
+	// filtered out of Members.
+	ActiveCount          int  `json:"active_count"`
+	TotalCount           int  `json:"total_count"`
+	IsArchived           bool `json:"is_archived,omitempty"`
+	DefaultReviewerCount int  `json:"default_reviewer_count"`
+}
+
+// CountMembers fills the counts from Members, which must hold the whole team;
+// filter Members afterwards and reset MemberCount to match.
+func (t *Team) CountMembers() {
+	t.MemberCount = len(t.Members)
+	t.TotalCount = len(t.Members)
+	t.ActiveCount = 0
+	for _, m := range t.Members {
+		if m.IsActive {
+			t.ActiveCount++
+		}
+	}
 }
 
 type User struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	UserID           string     `json:"user_id"`
+	Username         string     `json:"username"`
+	TeamName         string     `json:"team_name"`
+	IsActive         bool       `json:"is_active"`
+	MaxOpenReviews   *int       `json:"max_open_reviews,omitempty"`
+	UnavailableUntil *time.Time `json:"unavailable_until,omitempty"`
 }
 
 type PullRequest struct {
 	ID                string     `json:"pull_request_id"`
 	Name              string     `json:"pull_request_name"`
 	AuthorID          string     `json:"author_id"`
+	AuthorUsername    string     `json:"author_username"`
 	Status            string     `json:"status"`
 	AssignedReviewers []string   `json:"assigned_reviewers"`
+	ReviewersDetailed []Reviewer `json:"reviewers_detailed,omitempty"`
+	Approvals         []string   `json:"approvals"`
+	ChangesRequested  []string   `json:"changes_requested,omitempty"`
 	CreatedAt         *time.Time `json:"createdAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+}
+
+type Reviewer struct {
+	UserID     string    `json:"user_id"`
+	Username   string    `json:"username"`
+	IsActive   bool      `json:"is_active"`
+	AssignedAt time.Time `json:"assigned_at"`
 }
 
 type PullRequestShort struct {
-	ID       string `json:"pull_request_id"`
-	Name     string `json:"pull_request_name"`
-	AuthorID string `json:"author_id"`
-	Status   string `json:"status"`
+	ID             string     `json:"pull_request_id"`
+	Name           string     `json:"pull_request_name"`
+	AuthorID       string     `json:"author_id"`
+	AuthorUsername string     `json:"author_username"`
+	Status         string     `json:"status"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+	MergedAt       *time.Time `json:"mergedAt,omitempty"`
+}
+
+type Reassignment struct {
+	PullRequestID string    `json:"pull_request_id"`
+	OldUserID     string    `json:"old_user_id"`
+	NewUserID     string    `json:"new_user_id"`
+	ActorID       string    `json:"actor_id,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+const (
+	ReviewerAssigned = "ASSIGNED"
+	ReviewerRemoved  = "REMOVED"
+	ReviewerReplaced = "REPLACED"
+)
+
+type ReviewerHistoryEntry struct {
+	PullRequestID string    `json:"pull_request_id"`
+	UserID        string    `json:"user_id"`
+	Action        string    `json:"action"`
+	At            time.Time `json:"at"`
+}
+
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Operation string          `json:"operation"`
+	EntityIDs []string        `json:"entity_ids"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
 }
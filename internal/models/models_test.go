@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeTeam(body string) (Team, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.DisallowUnknownFields()
+	var team Team
+	err := dec.Decode(&team)
+	return team, err
+}
+
+func TestMemberListShapesDecodeIdentically(t *testing.T) {
+	array, err := decodeTeam(`{"team_name":"backend","members":[
+		{"user_id":"u2","username":"Bob","is_active":false},
+		{"user_id":"u1","username":"Alice","is_active":true,"role":"backend"}]}`)
+	if err != nil {
+		t.Fatalf("array form: %v", err)
+	}
+	object, err := decodeTeam(`{"team_name":"backend","members":{
+		"u2":{"username":"Bob","is_active":false},
+		"u1":{"user_id":"u1","username":"Alice","is_active":true,"role":"backend"}}}`)
+	if err != nil {
+		t.Fatalf("object form: %v", err)
+	}
+	if !reflect.DeepEqual(array, object) {
+		t.Fatalf("array form %+v != object form %+v", array, object)
+	}
+	// the object form keeps the order of its keys
+	if object.Members[0].UserID != "u2" || object.Members[1].UserID != "u1" {
+		t.Fatalf("members = %+v, want u2 then u1", object.Members)
+	}
+
+	empty, err := decodeTeam(`{"team_name":"backend","members":{}}`)
+	if err != nil || empty.Members == nil || len(empty.Members) != 0 {
+		t.Fatalf("empty object = %#v, %v; want an empty list", empty.Members, err)
+	}
+}
+
+func TestMemberListRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "unknown member field in array", body: `{"team_name":"t","members":[{"user_id":"u1","username":"a","is_active":true,"admin":true}]}`},
+		{name: "unknown member field in object", body: `{"team_name":"t","members":{"u1":{"username":"a","is_active":true,"admin":true}}}`},
+		{name: "unknown team field", body: `{"team_name":"t","members":[],"owner":"u1"}`},
+		{name: "mismatched user_id", body: `{"team_name":"t","members":{"u1":{"user_id":"u2","username":"a"}}}`},
+		{name: "scalar", body: `{"team_name":"t","members":"u1"}`},
+		{name: "type error in array", body: `{"team_name":"t","members":[{"user_id":"u1","is_active":"yes"}]}`, field: "members.0.is_active"},
+		{name: "type error in object", body: `{"team_name":"t","members":{"u1":{"username":7}}}`, field: "members.u1.username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTeam(tt.body)
+			if err == nil {
+				t.Fatalf("decoded %s", tt.body)
+			}
+			if tt.field == "" {
+				return
+			}
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) || typeErr.Field != tt.field {
+				t.Fatalf("err = %v, want a type error at %s", err, tt.field)
+			}
+		})
+	}
+}
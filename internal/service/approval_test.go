@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestApprovePullRequest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	reviewer := pr.AssignedReviewers[0]
+
+	approved, err := s.ApprovePullRequest(ctx, pr.ID, reviewer)
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if len(approved.Approvals) != 1 || approved.Approvals[0] != reviewer {
+		t.Fatalf("approvals = %v, want [%s]", approved.Approvals, reviewer)
+	}
+	again, err := s.ApprovePullRequest(ctx, pr.ID, reviewer)
+	if err != nil {
+		t.Fatalf("approve twice: %v", err)
+	}
+	if len(again.Approvals) != 1 {
+		t.Fatalf("second approval changed approvals to %v", again.Approvals)
+	}
+
+	_, err = s.ApprovePullRequest(ctx, pr.ID, "u1")
+	assertCode(t, err, CodeNotAssigned)
+
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	_, err = s.ApprovePullRequest(ctx, pr.ID, reviewer)
+	assertCode(t, err, CodePRMerged)
+
+	_, err = s.ApprovePullRequest(ctx, "missing", reviewer)
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestPullRequestApprovalsNeverNull(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	created := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	loaded, err := s.GetPullRequest(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	merged, err := s.MergePullRequest(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	for name, pr := range map[string]models.PullRequest{"create": created, "get": loaded, "merge": merged} {
+		data, err := json.Marshal(pr)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		if !strings.Contains(string(data), `"approvals":[]`) {
+			t.Errorf("%s: approvals not an empty list in %s", name, data)
+		}
+	}
+}
+
+func TestMergeRequiresMinApprovals(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	s.SetMinApprovals(2)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2", "u3")
+
+	if _, err := s.ApprovePullRequest(ctx, pr.ID, "u2"); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	_, err := s.MergePullRequest(ctx, pr.ID)
+	assertCode(t, err, CodeInsufficientApprovals)
+	if got, err := s.GetPullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("get: %v", err)
+	} else if got.Status != models.StatusOpen {
+		t.Fatalf("status after rejected merge = %s, want OPEN", got.Status)
+	}
+
+	if _, err := s.ApprovePullRequest(ctx, pr.ID, "u3"); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	merged, err := s.MergePullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("merge at threshold: %v", err)
+	}
+	if merged.Status != models.StatusMerged {
+		t.Fatalf("status = %s, want MERGED", merged.Status)
+	}
+}
+
+func TestMergeWithoutThreshold(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+
+	if _, err := s.MergePullRequest(context.Background(), pr.ID); err != nil {
+		t.Fatalf("merge without approvals: %v", err)
+	}
+}
+
+func TestRequestedChangesBlockMerge(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	a, b := pr.AssignedReviewers[0], pr.AssignedReviewers[1]
+
+	if _, err := s.RequestChanges(ctx, pr.ID, a); err != nil {
+		t.Fatalf("request changes: %v", err)
+	}
+	_, err := s.MergePullRequest(ctx, pr.ID)
+	assertCode(t, err, CodeChangesRequested)
+
+	// another reviewer's approval does not supersede the request
+	if _, err := s.ApprovePullRequest(ctx, pr.ID, b); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	_, err = s.MergePullRequest(ctx, pr.ID)
+	assertCode(t, err, CodeChangesRequested)
+
+	if _, err := s.ApprovePullRequest(ctx, pr.ID, a); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	merged, err := s.MergePullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("merge after approval: %v", err)
+	}
+	if merged.Status != models.StatusMerged {
+		t.Fatalf("status = %s, want MERGED", merged.Status)
+	}
+
+	_, err = s.RequestChanges(ctx, pr.ID, a)
+	assertCode(t, err, CodePRMerged)
+	_, err = s.RequestChanges(ctx, "missing", a)
+	assertCode(t, err, CodeNotFound)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+const (
+	AuditTeamCreate       = "team.create"
+	AuditTeamDelete       = "team.delete"
+	AuditUserSetActive    = "user.set_active"
+	AuditPRCreate         = "pr.create"
+	AuditPRMerge          = "pr.merge"
+	AuditPRClose          = "pr.close"
+	AuditPRDelete         = "pr.delete"
+	AuditReviewerReassign = "pr.reassign"
+)
+
+// insertAudit must run in the caller's transaction so that rolled-back
+// operations leave no audit entries.
+func insertAudit(ctx context.Context, tx *sql.Tx, operation string, entityIDs []string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s audit entry: %w", operation, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_log (operation, entity_ids, payload) VALUES ($1, $2, $3)`,
+		operation, pq.Array(entityIDs), data,
+	); err != nil {
+		return fmt.Errorf("insert %s audit entry: %w", operation, err)
+	}
+	return nil
+}
+
+// AuditFilter narrows GetAuditLog; empty fields match everything.
+type AuditFilter struct {
+	Operation string
+	EntityID  string
+	Limit     int
+	Offset    int
+}
+
+func (s *Service) GetAuditLog(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	const where = `WHERE ($1 = '' OR operation = $1)
+		   AND ($2 = '' OR $2 = ANY(entity_ids))`
+
+	var total int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM audit_log `+where,
+		filter.Operation, filter.EntityID,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, operation, entity_ids, payload, created_at
+		 FROM audit_log
+		 `+where+`
+		 ORDER BY id DESC
+		 LIMIT $3 OFFSET $4`,
+		filter.Operation, filter.EntityID, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Operation, pq.Array(&e.EntityIDs), &e.Payload, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		entries = append(entries, e)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+	return entries, total, nil
+}
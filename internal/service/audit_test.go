@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func auditOperations(t *testing.T, s *Service, filter AuditFilter) []string {
+	t.Helper()
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+	entries, total, err := s.GetAuditLog(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if total != len(entries) {
+		t.Fatalf("total = %d with %d entries", total, len(entries))
+	}
+	ops := make([]string, 0, len(entries))
+	// newest first; reverse to read in the order the operations ran
+	for i := len(entries) - 1; i >= 0; i-- {
+		ops = append(ops, entries[i].Operation)
+	}
+	return ops
+}
+
+func TestAuditRowPerMutation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	mustReassign(t, s, pr.ID, pr.AssignedReviewers[0])
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if _, err := s.SetUserActive(ctx, "u4", false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+
+	// failed mutations roll back together with their audit rows
+	_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-1", Name: "again", Author: "u1"})
+	assertCode(t, err, CodePRExists)
+	_, err = s.SetUserActive(ctx, "missing", false)
+	assertCode(t, err, CodeNotFound)
+
+	want := []string{AuditTeamCreate, AuditPRCreate, AuditReviewerReassign, AuditPRMerge, AuditUserSetActive}
+	if got := auditOperations(t, s, AuditFilter{}); !slices.Equal(got, want) {
+		t.Fatalf("audit = %v, want %v", got, want)
+	}
+	if got := auditOperations(t, s, AuditFilter{EntityID: "pr-1"}); !slices.Equal(got, want[1:4]) {
+		t.Fatalf("audit for pr-1 = %v, want %v", got, want[1:4])
+	}
+	if got := auditOperations(t, s, AuditFilter{Operation: AuditPRMerge}); !slices.Equal(got, []string{AuditPRMerge}) {
+		t.Fatalf("merge audit = %v", got)
+	}
+}
+
+func TestAuditRowPerDestructiveMutation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "f1"})
+
+	if _, err := s.DeletePullRequest(ctx, "pr-1", false); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.ClosePullRequest(ctx, "pr-2"); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// closing again changes nothing and is not audited
+	if _, err := s.ClosePullRequest(ctx, "pr-2"); err != nil {
+		t.Fatalf("close again: %v", err)
+	}
+	if _, err := s.DeleteTeam(ctx, "frontend"); err != nil {
+		t.Fatalf("delete team: %v", err)
+	}
+
+	// failed mutations leave no rows
+	_, err := s.DeletePullRequest(ctx, "missing", true)
+	assertCode(t, err, CodeNotFound)
+	_, err = s.DeleteTeam(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+
+	want := []string{AuditPRDelete, AuditPRClose, AuditTeamDelete}
+	got := auditOperations(t, s, AuditFilter{})
+	if len(got) < len(want) || !slices.Equal(got[len(got)-len(want):], want) {
+		t.Fatalf("audit = %v, want it to end with %v", got, want)
+	}
+	if got := auditOperations(t, s, AuditFilter{Operation: AuditTeamDelete, EntityID: "f2"}); !slices.Equal(got, []string{AuditTeamDelete}) {
+		t.Fatalf("team delete audit for f2 = %v", got)
+	}
+	if got := auditOperations(t, s, AuditFilter{EntityID: "pr-1"}); !slices.Equal(got, []string{AuditPRCreate, AuditPRDelete}) {
+		t.Fatalf("audit for pr-1 = %v", got)
+	}
+}
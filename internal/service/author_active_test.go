@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCreatePullRequestAuthorMustBeActive(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), inactiveMember("bot"), activeMember("u2"), activeMember("u3"))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2", "u3")
+
+	_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-bot", Name: "PR", Author: "bot"})
+	assertCode(t, err, CodeAuthorInactive)
+	_, err = s.GetPullRequest(ctx, "pr-bot")
+	assertCode(t, err, CodeNotFound)
+
+	// teams that let bots open PRs switch the check off
+	s.SetRequireActiveAuthor(false)
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-bot", Author: "bot"})
+	if len(pr.AssignedReviewers) != 2 || slices.Contains(pr.AssignedReviewers, "bot") {
+		t.Fatalf("reviewers = %v, want two active teammates", pr.AssignedReviewers)
+	}
+}
+
+func TestCreatePullRequestAuthorWithoutTeam(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	mustCreateTeam(t, s, "ghost", activeMember("g1"), activeMember("g2"))
+	// the foreign key rules this out today; drop it to reach the guard
+	mustExec(t, s, `ALTER TABLE users DROP CONSTRAINT users_team_name_fkey`)
+	mustExec(t, s, `DELETE FROM teams WHERE team_name = 'ghost'`)
+	mustExec(t, s, `UPDATE users SET team_name = '' WHERE user_id = 'u2'`)
+
+	for _, author := range []string{"g1", "u2"} {
+		_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-" + author, Name: "PR", Author: author})
+		assertCode(t, err, CodeAuthorNoTeam)
+		_, err = s.GetPullRequest(ctx, "pr-"+author)
+		assertCode(t, err, CodeNotFound)
+	}
+}
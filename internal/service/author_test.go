@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestAuthorUsername(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	if pr.AuthorUsername != "user-u1" {
+		t.Fatalf("created PR author_username = %q", pr.AuthorUsername)
+	}
+
+	renamed := "alice"
+	if _, err := s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "u1", Username: &renamed}); err != nil {
+		t.Fatalf("rename author: %v", err)
+	}
+
+	got, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	if got.AuthorUsername != renamed {
+		t.Fatalf("author_username after rename = %q, want %q", got.AuthorUsername, renamed)
+	}
+	reassigned, _, err := s.ReassignReviewer(ctx, pr.ID, pr.AssignedReviewers[0])
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if reassigned.AuthorUsername != renamed {
+		t.Fatalf("reassigned PR author_username = %q", reassigned.AuthorUsername)
+	}
+	reviews, _, err := s.ListUserReviews(ctx, reassigned.AssignedReviewers[0], 10, 0)
+	if err != nil {
+		t.Fatalf("list reviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].AuthorUsername != renamed {
+		t.Fatalf("reviews = %+v", reviews)
+	}
+	merged, err := s.MergePullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if merged.AuthorUsername != renamed {
+		t.Fatalf("merged PR author_username = %q", merged.AuthorUsername)
+	}
+}
+
+func TestReviewerUsernames(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+
+	check := func(pr models.PullRequest) {
+		t.Helper()
+		if len(pr.ReviewersDetailed) != len(pr.AssignedReviewers) {
+			t.Fatalf("%s: detailed = %+v, assigned = %v", pr.ID, pr.ReviewersDetailed, pr.AssignedReviewers)
+		}
+		for _, r := range pr.ReviewersDetailed {
+			if r.Username != "user-"+r.UserID {
+				t.Fatalf("%s: reviewer %s has username %q", pr.ID, r.UserID, r.Username)
+			}
+		}
+	}
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	check(pr)
+	pr, _, err := s.ReassignReviewer(ctx, pr.ID, pr.AssignedReviewers[0])
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	check(pr)
+	pr, err = s.MergePullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	check(pr)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func capped(m models.TeamMember, limit int) models.TeamMember {
+	m.MaxOpenReviews = &limit
+	return m
+}
+
+func TestWithinCapacity(t *testing.T) {
+	limit := func(n int64) sql.NullInt64 { return sql.NullInt64{Int64: n, Valid: true} }
+	tests := []struct {
+		name       string
+		serviceCap int
+		candidates []candidate
+		want       []string
+	}{
+		{
+			name: "own cap",
+			candidates: []candidate{
+				{ID: "u1", OpenReviews: 2, MaxOpenReviews: limit(2)},
+				{ID: "u2", OpenReviews: 5},
+			},
+			want: []string{"u2"},
+		},
+		{
+			name:       "service cap",
+			serviceCap: 3,
+			candidates: []candidate{
+				{ID: "u1", OpenReviews: 3},
+				{ID: "u2", OpenReviews: 2},
+			},
+			want: []string{"u2"},
+		},
+		{
+			name:       "everyone at cap falls back to least loaded",
+			serviceCap: 1,
+			candidates: []candidate{
+				{ID: "u1", OpenReviews: 4},
+				{ID: "u2", OpenReviews: 1},
+				{ID: "u3", OpenReviews: 1, MaxOpenReviews: limit(1)},
+			},
+			want: []string{"u2", "u3"},
+		},
+		{name: "no candidates", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewWithRand(nil, nil)
+			s.SetMaxOpenReviews(tt.serviceCap)
+			if got := s.withinCapacity(tt.candidates); !slices.Equal(got, tt.want) {
+				t.Fatalf("withinCapacity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequestSkipsReviewersAtCap(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), capped(activeMember("u2"), 0), activeMember("u3"), activeMember("u4"))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u3", "u4")
+}
+
+func TestCreatePullRequestIgnoresCapWhenEveryoneIsAtIt(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), capped(activeMember("u2"), 0), capped(activeMember("u3"), 0))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2", "u3")
+}
+
+func TestReassignSkipsReviewersAtCap(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), capped(activeMember("u4"), 0))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	old := pr.AssignedReviewers[0]
+	if old == "u4" {
+		t.Fatalf("reviewer at cap was assigned")
+	}
+	_, replacedBy, err := s.ReassignReviewer(context.Background(), pr.ID, old)
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	if replacedBy == "u4" || replacedBy == old {
+		t.Fatalf("replaced %s by %s", old, replacedBy)
+	}
+}
+
+func TestUnavailableUntil(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+
+	user, err := s.SetUserUnavailable(ctx, "u2", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("set unavailable: %v", err)
+	}
+	if user.UnavailableUntil == nil {
+		t.Fatalf("unavailable_until not returned")
+	}
+	if _, err := s.SetUserUnavailable(ctx, "u3", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("set unavailable: %v", err)
+	}
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u3")
+
+	if _, err := s.SetUserUnavailable(ctx, "u2", time.Time{}); err != nil {
+		t.Fatalf("clear unavailable: %v", err)
+	}
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u1"})
+	assertReviewers(t, pr, "u2", "u3")
+
+	_, err = s.SetUserUnavailable(ctx, "missing", time.Time{})
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestCreatePullRequestSkipsReviewersAtServiceCap(t *testing.T) {
+	s := newTestService(t)
+	s.SetMaxOpenReviews(1)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+
+	first := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	busy := first.AssignedReviewers[0]
+
+	second := mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u1"})
+	if slices.Contains(second.AssignedReviewers, busy) {
+		t.Fatalf("reviewers = %v, %s is at the cap", second.AssignedReviewers, busy)
+	}
+	if len(second.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers = %v, want 2", second.AssignedReviewers)
+	}
+
+	// everyone is at the cap now: the PR still gets the least loaded reviewers
+	third := mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "u1", ReviewerCount: 1})
+	if len(third.AssignedReviewers) != 1 {
+		t.Fatalf("reviewers = %v, want a fallback pick", third.AssignedReviewers)
+	}
+}
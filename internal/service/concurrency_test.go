@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Run with -race: the shared rand source must be guarded.
+func TestPickRandomConcurrently(t *testing.T) {
+	s := NewWithRand(nil, rand.New(rand.NewSource(1)))
+	ids := []string{"u1", "u2", "u3", "u4", "u5"}
+
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				got := s.pickRandom(slices.Clone(ids), 2)
+				if len(got) != 2 || got[0] == got[1] {
+					t.Errorf("pickRandom = %v", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentPullRequestCreation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"),
+		activeMember("u4"), activeMember("u5"))
+
+	const n = 40
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			author := fmt.Sprintf("u%d", i%5+1)
+			pr, err := s.CreatePullRequest(ctx, CreatePRInput{ID: fmt.Sprintf("pr-%d", i), Name: "PR", Author: author})
+			if err == nil && (len(pr.AssignedReviewers) != 2 || slices.Contains(pr.AssignedReviewers, author)) {
+				err = fmt.Errorf("%s by %s got reviewers %v", pr.ID, author, pr.AssignedReviewers)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM pr_reviewers`).Scan(&total); err != nil {
+		t.Fatalf("count reviewers: %v", err)
+	}
+	if total != 2*n {
+		t.Fatalf("pr_reviewers rows = %d, want %d", total, 2*n)
+	}
+}
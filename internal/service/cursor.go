@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ReviewCursor marks the last PR of a keyset page: the next page starts
+// strictly after it in (created_at, pull_request_id) descending order.
+type ReviewCursor struct {
+	CreatedAt     time.Time
+	PullRequestID string
+}
+
+var errInvalidCursor = errors.New("cursor is malformed")
+
+func (c ReviewCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.PullRequestID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseReviewCursor decodes a cursor produced by ReviewCursor.String.
+func ParseReviewCursor(s string) (ReviewCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ReviewCursor{}, errInvalidCursor
+	}
+	ts, prID, ok := strings.Cut(string(raw), "|")
+	if !ok || prID == "" {
+		return ReviewCursor{}, errInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return ReviewCursor{}, errInvalidCursor
+	}
+	return ReviewCursor{CreatedAt: createdAt, PullRequestID: prID}, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReviewCursorRoundTrip(t *testing.T) {
+	c := ReviewCursor{
+		CreatedAt:     time.Date(2025, 4, 2, 9, 30, 0, 123456000, time.FixedZone("MSK", 3*3600)),
+		PullRequestID: "pr|odd-id",
+	}
+	got, err := ParseReviewCursor(c.String())
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !got.CreatedAt.Equal(c.CreatedAt) || got.PullRequestID != c.PullRequestID {
+		t.Fatalf("round trip = %+v, want %+v", got, c)
+	}
+
+	for _, bad := range []string{"", "!!!", "bm90LWEtY3Vyc29y", "MjAyNS0wNC0wMlQwOTozMDowMFp8"} {
+		if _, err := ParseReviewCursor(bad); err == nil {
+			t.Errorf("ParseReviewCursor(%q) accepted a malformed cursor", bad)
+		}
+	}
+}
+
+func TestListUserReviewsAfterWalksAllPages(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var want []string
+	for i := range 8 {
+		id := fmt.Sprintf("pr-%d", i)
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+		// pairs share a timestamp, so the id tie-break is exercised as well
+		setPRTimes(t, s, id, base.Add(time.Duration(i/2)*time.Hour), time.Time{})
+		want = append(want, id)
+	}
+	// newest first, ties broken by id descending
+	slices.Reverse(want)
+
+	var got []string
+	var cursor *ReviewCursor
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatalf("cursor walk did not terminate: %v", got)
+		}
+		prs, next, err := s.ListUserReviewsAfter(ctx, "u2", cursor, 3)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if len(prs) > 3 {
+			t.Fatalf("page %d has %d items, limit 3", page, len(prs))
+		}
+		for _, pr := range prs {
+			got = append(got, pr.ID)
+		}
+		if next == nil {
+			break
+		}
+		// go through the wire format like a client would
+		parsed, err := ParseReviewCursor(next.String())
+		if err != nil {
+			t.Fatalf("page %d cursor: %v", page, err)
+		}
+		cursor = &parsed
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("walk = %v, want %v without duplicates or gaps", got, want)
+	}
+
+	// an exact page boundary ends without an empty trailing page
+	prs, next, err := s.ListUserReviewsAfter(ctx, "u2", nil, len(want))
+	if err != nil {
+		t.Fatalf("full page: %v", err)
+	}
+	if len(prs) != len(want) || next != nil {
+		t.Fatalf("full page = %d items, next %v", len(prs), next)
+	}
+
+	_, _, err = s.ListUserReviewsAfter(ctx, "missing", nil, 3)
+	assertCode(t, err, CodeNotFound)
+}
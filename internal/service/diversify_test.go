@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiversifyAvoidsRecentReviewers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	merged := mustCreatePR(t, s, CreatePRInput{ID: "pr-merged", Author: "u1", ReviewerCount: 1})
+	if _, err := s.MergePullRequest(ctx, merged.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	recent := merged.AssignedReviewers[0]
+	other := "u2"
+	if recent == "u2" {
+		other = "u3"
+	}
+
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1", ReviewerCount: 1, Diversify: true})
+		assertReviewers(t, pr, other)
+	}
+
+	// the recent reviewer still fills a slot nobody else can take
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-both", Author: "u1", Diversify: true})
+	assertReviewers(t, pr, "u2", "u3")
+
+	// open PRs are not part of the window, and other authors are unaffected
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-author", Author: other, ReviewerCount: 2, Diversify: true})
+	assertReviewers(t, pr, "u1", recent)
+}
+
+func TestDiversifyKeepsOnlyCandidate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "pair", activeMember("u1"), activeMember("u2"))
+	for _, id := range []string{"pr-1", "pr-2"} {
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+		if _, err := s.MergePullRequest(ctx, id); err != nil {
+			t.Fatalf("merge %s: %v", id, err)
+		}
+	}
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "u1", Diversify: true})
+	assertReviewers(t, pr, "u2")
+}
+
+func TestAvoidRepeatReviewers(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+
+	prev := mustCreatePR(t, s, CreatePRInput{ID: "pr-0", Author: "u1", ReviewerCount: 1})
+	for _, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1", ReviewerCount: 1, AvoidRepeatReviewers: true})
+		if pr.AssignedReviewers[0] == prev.AssignedReviewers[0] {
+			t.Fatalf("%s: %s reviewed the author's previous PR %s too", id, pr.AssignedReviewers[0], prev.ID)
+		}
+		prev = pr
+	}
+
+	// the previous reviewer still fills a slot nobody else can take
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-5", Author: "u1", ReviewerCount: 2})
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-6", Author: "u1", ReviewerCount: 3, AvoidRepeatReviewers: true})
+	assertReviewers(t, pr, "u2", "u3", "u4")
+}
+
+func TestAvoidRepeatReviewersKeepsOnlyCandidate(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "pair", activeMember("u1"), activeMember("u2"))
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u1", AvoidRepeatReviewers: true})
+	assertReviewers(t, pr, "u2")
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+const (
+	EventPRCreated          = "pr.created"
+	EventPRMerged           = "pr.merged"
+	EventReviewerReassigned = "reviewer.reassigned"
+)
+
+func insertEvent(ctx context.Context, tx *sql.Tx, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO events (event_type, payload) VALUES ($1, $2)`,
+		eventType, data,
+	); err != nil {
+		return fmt.Errorf("insert %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+func (s *Service) ListEvents(ctx context.Context, afterID int64, limit int) ([]models.Event, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, event_type, payload, created_at
+		 FROM events
+		 WHERE id > $1
+		 ORDER BY id
+		 LIMIT $2`,
+		afterID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.Event{}
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		events = append(events, e)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return events, nil
+}
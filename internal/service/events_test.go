@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func eventTypes(t *testing.T, s *Service) []string {
+	t.Helper()
+	events, err := s.ListEvents(context.Background(), 0, 100)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func assertEventTypes(t *testing.T, s *Service, want ...string) {
+	t.Helper()
+	got := eventTypes(t, s)
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEventsFollowCommittedChanges(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	assertEventTypes(t, s)
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	assertEventTypes(t, s, EventPRCreated)
+
+	_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-1", Name: "again", Author: "u1"})
+	assertCode(t, err, CodePRExists)
+	assertEventTypes(t, s, EventPRCreated)
+
+	mustReassign(t, s, pr.ID, pr.AssignedReviewers[0])
+	assertEventTypes(t, s, EventPRCreated, EventReviewerReassigned)
+
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	// merging again is a no-op and must not emit a second event
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge again: %v", err)
+	}
+	assertEventTypes(t, s, EventPRCreated, EventReviewerReassigned, EventPRMerged)
+}
+
+func TestEventRolledBackWithFailedTransaction(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	// the audit insert runs after the event insert in the same transaction,
+	// so breaking it makes the create fail once the event row is written
+	mustExec(t, s, `DROP TABLE audit_log`)
+
+	if _, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-1", Name: "PR", Author: "u1"}); err == nil {
+		t.Fatalf("create succeeded without the audit table")
+	}
+	assertEventTypes(t, s)
+	_, err := s.GetPullRequest(ctx, "pr-1")
+	assertCode(t, err, CodeNotFound)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestListPullRequests(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+
+	day := func(n int) time.Time { return time.Date(2025, 4, n, 12, 0, 0, 0, time.UTC) }
+	for _, pr := range []struct {
+		id, author    string
+		created, done int
+	}{
+		{id: "b-1", author: "u1", created: 1},
+		{id: "b-2", author: "u2", created: 2, done: 5},
+		{id: "f-1", author: "f1", created: 3, done: 4},
+		{id: "f-2", author: "f2", created: 4},
+	} {
+		mustCreatePR(t, s, CreatePRInput{ID: pr.id, Author: pr.author})
+		var merged time.Time
+		if pr.done > 0 {
+			if _, err := s.MergePullRequest(ctx, pr.id); err != nil {
+				t.Fatalf("merge %s: %v", pr.id, err)
+			}
+			merged = day(pr.done)
+		}
+		setPRTimes(t, s, pr.id, day(pr.created), merged)
+	}
+
+	tests := []struct {
+		name   string
+		filter PRFilter
+		want   []string
+		total  int
+	}{
+		{name: "all by created_at", filter: PRFilter{}, want: []string{"f-2", "f-1", "b-2", "b-1"}, total: 4},
+		{name: "all by merged_at", filter: PRFilter{Sort: SortMergedAt}, want: []string{"b-2", "f-1", "f-2", "b-1"}, total: 4},
+		{name: "status", filter: PRFilter{Status: models.StatusMerged}, want: []string{"f-1", "b-2"}, total: 2},
+		{name: "author", filter: PRFilter{AuthorID: "u1"}, want: []string{"b-1"}, total: 1},
+		{name: "team", filter: PRFilter{TeamName: "backend"}, want: []string{"b-2", "b-1"}, total: 2},
+		{name: "team and status", filter: PRFilter{TeamName: "frontend", Status: models.StatusOpen}, want: []string{"f-2"}, total: 1},
+		{name: "author outside team", filter: PRFilter{TeamName: "frontend", AuthorID: "u1"}, want: []string{}, total: 0},
+		{name: "unknown team", filter: PRFilter{TeamName: "missing"}, want: []string{}, total: 0},
+		{name: "page", filter: PRFilter{Limit: 2, Offset: 1}, want: []string{"f-1", "b-2"}, total: 4},
+		{name: "past the end", filter: PRFilter{Offset: 10}, want: []string{}, total: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.filter.Limit == 0 {
+				tt.filter.Limit = 10
+			}
+			prs, total, err := s.ListPullRequests(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			got := make([]string, 0, len(prs))
+			for _, pr := range prs {
+				got = append(got, pr.ID)
+			}
+			if !slices.Equal(got, tt.want) || total != tt.total {
+				t.Fatalf("got %v (total %d), want %v (total %d)", got, total, tt.want, tt.total)
+			}
+		})
+	}
+}
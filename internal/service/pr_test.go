@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestGetPullRequest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	created := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+
+	got, err := s.GetPullRequest(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("get open PR: %v", err)
+	}
+	if got.Status != models.StatusOpen || got.AuthorID != "u1" || got.CreatedAt == nil || got.MergedAt != nil {
+		t.Fatalf("open PR = %+v", got)
+	}
+	assertReviewers(t, got, created.AssignedReviewers...)
+
+	if _, err := s.MergePullRequest(ctx, "pr-1"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	got, err = s.GetPullRequest(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("get merged PR: %v", err)
+	}
+	if got.Status != models.StatusMerged || got.MergedAt == nil {
+		t.Fatalf("merged PR = %+v", got)
+	}
+	assertReviewers(t, got, created.AssignedReviewers...)
+
+	_, err = s.GetPullRequest(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestDeletePullRequest(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreatePR(t, s, CreatePRInput{ID: "open", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "merged", Author: "u1"})
+	if _, err := s.MergePullRequest(ctx, "merged"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+
+	deleted, err := s.DeletePullRequest(ctx, "open", false)
+	if err != nil {
+		t.Fatalf("delete open PR: %v", err)
+	}
+	if deleted.ID != "open" || len(deleted.AssignedReviewers) != 2 {
+		t.Fatalf("deleted PR = %+v", deleted)
+	}
+	_, err = s.GetPullRequest(ctx, "open")
+	assertCode(t, err, CodeNotFound)
+	var reviewers int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM pr_reviewers WHERE pull_request_id = 'open'`).Scan(&reviewers); err != nil {
+		t.Fatalf("count reviewers: %v", err)
+	}
+	if reviewers != 0 {
+		t.Fatalf("%d reviewer rows left after delete", reviewers)
+	}
+
+	_, err = s.DeletePullRequest(ctx, "merged", false)
+	assertCode(t, err, CodePRMerged)
+	if _, err := s.DeletePullRequest(ctx, "merged", true); err != nil {
+		t.Fatalf("force delete merged PR: %v", err)
+	}
+	_, err = s.GetPullRequest(ctx, "merged")
+	assertCode(t, err, CodeNotFound)
+
+	_, err = s.DeletePullRequest(ctx, "missing", true)
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestTimestampsSerializeInUTC(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	// one connection so the session time zone applies to every query
+	s.db.SetMaxOpenConns(1)
+	mustExec(t, s, `SET TIME ZONE 'Europe/Moscow'`)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+
+	created := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	reassigned, _, err := s.ReassignReviewer(ctx, "pr-1", created.AssignedReviewers[0])
+	if err != nil {
+		t.Fatalf("reassign: %v", err)
+	}
+	merged, err := s.MergePullRequest(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	got, err := s.GetPullRequest(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	for name, pr := range map[string]models.PullRequest{"create": created, "reassign": reassigned, "merge": merged, "get": got} {
+		data, err := json.Marshal(pr)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		for _, field := range []string{"createdAt", "mergedAt"} {
+			v, ok := raw[field].(string)
+			if !ok {
+				if field == "mergedAt" && pr.Status == models.StatusOpen {
+					continue
+				}
+				t.Fatalf("%s: %s missing in %s", name, field, data)
+			}
+			if !strings.HasSuffix(v, "Z") {
+				t.Fatalf("%s: %s = %q, want UTC with a Z suffix", name, field, v)
+			}
+		}
+	}
+}
+
+func TestUpdatedAtTracksChanges(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	if pr.UpdatedAt == nil {
+		t.Fatalf("created PR has no updated_at")
+	}
+	// move it into the past so every later write clearly advances it
+	past := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+	mustExec(t, s, `UPDATE pull_requests SET updated_at = $1`, past)
+	updatedAt := func() time.Time {
+		t.Helper()
+		got, err := s.GetPullRequest(ctx, pr.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		return *got.UpdatedAt
+	}
+
+	if got := updatedAt(); !got.Equal(past) {
+		t.Fatalf("updated_at = %s after a read, want %s", got, past)
+	}
+
+	mustReassign(t, s, pr.ID, pr.AssignedReviewers[0])
+	afterReassign := updatedAt()
+	if !afterReassign.After(past) {
+		t.Fatalf("updated_at = %s after reassign, want later than %s", afterReassign, past)
+	}
+
+	mustExec(t, s, `UPDATE pull_requests SET updated_at = $1`, past)
+	merged, err := s.MergePullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if !merged.UpdatedAt.After(past) || !updatedAt().Equal(*merged.UpdatedAt) {
+		t.Fatalf("updated_at = %s after merge, want later than %s", merged.UpdatedAt, past)
+	}
+}
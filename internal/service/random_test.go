@@ -0,0 +1,63 @@
+package service
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+)
+
+func TestPickRandomSameSeedSameAssignments(t *testing.T) {
+	a := NewWithRand(nil, rand.New(rand.NewSource(42)))
+	b := NewWithRand(nil, rand.New(rand.NewSource(42)))
+	ids := []string{"u1", "u2", "u3", "u4", "u5", "u6"}
+
+	for i := 0; i < 20; i++ {
+		gotA := a.pickRandom(slices.Clone(ids), 2)
+		gotB := b.pickRandom(slices.Clone(ids), 2)
+		if !slices.Equal(gotA, gotB) {
+			t.Fatalf("pick %d: %v != %v", i, gotA, gotB)
+		}
+	}
+}
+
+func TestPickRandomLimits(t *testing.T) {
+	s := NewWithRand(nil, rand.New(rand.NewSource(1)))
+	if got := s.pickRandom(nil, 2); got == nil || len(got) != 0 {
+		t.Fatalf("pickRandom(nil) = %#v, want empty slice", got)
+	}
+	if got := s.pickRandom([]string{"u1", "u2"}, 0); len(got) != 0 {
+		t.Fatalf("pickRandom with limit 0 = %v", got)
+	}
+	got := s.pickRandom([]string{"u1", "u2"}, 5)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"u1", "u2"}) {
+		t.Fatalf("pickRandom above pool size = %v", got)
+	}
+}
+
+func TestSameSeedSameReassignments(t *testing.T) {
+	run := func() []string {
+		s := NewWithRand(dbtest.Open(t), rand.New(rand.NewSource(42)))
+		mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"), activeMember("u5"))
+		pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+		picks := []string{pr.AssignedReviewers[0]}
+		for i := 0; i < 3; i++ {
+			picks = append(picks, mustReassign(t, s, pr.ID, picks[len(picks)-1]))
+		}
+		return picks
+	}
+
+	first, second := run(), run()
+	if !slices.Equal(first, second) {
+		t.Fatalf("same seed gave %v and %v", first, second)
+	}
+}
+
+func TestNewWithRandDefaultsSource(t *testing.T) {
+	s := NewWithRand(nil, nil)
+	if got := s.pickRandom([]string{"u1", "u2", "u3"}, 2); len(got) != 2 {
+		t.Fatalf("pickRandom without an injected source = %v", got)
+	}
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func mustReassign(t *testing.T, s *Service, prID, oldUserID string) string {
+	t.Helper()
+	_, replacedBy, err := s.ReassignReviewer(context.Background(), prID, oldUserID)
+	if err != nil {
+		t.Fatalf("reassign %s on %s: %v", oldUserID, prID, err)
+	}
+	return replacedBy
+}
+
+func TestReassignmentHistoryIsOrdered(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+
+	first := pr.AssignedReviewers[0]
+	second := mustReassign(t, s, pr.ID, first)
+	third := mustReassign(t, s, pr.ID, second)
+
+	history, err := s.ReassignmentHistory(context.Background(), pr.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history = %+v, want 2 entries", history)
+	}
+	if history[0].OldUserID != first || history[0].NewUserID != second ||
+		history[1].OldUserID != second || history[1].NewUserID != third {
+		t.Fatalf("history = %+v, want %s->%s then %s->%s", history, first, second, second, third)
+	}
+	if history[1].CreatedAt.Before(history[0].CreatedAt) {
+		t.Fatalf("history timestamps out of order: %+v", history)
+	}
+
+	_, err = s.ReassignmentHistory(context.Background(), "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestReassignSkipsPreviouslyRemovedReviewer(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+
+	for i, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1", ReviewerCount: 1})
+		a := pr.AssignedReviewers[0]
+		b := mustReassign(t, s, pr.ID, a)
+		c := mustReassign(t, s, pr.ID, b)
+		if c == a {
+			t.Fatalf("PR %d: %s was picked again although another candidate existed", i, a)
+		}
+		if c == "u1" {
+			t.Fatalf("PR %d: author picked as reviewer", i)
+		}
+	}
+}
+
+func TestReassignFallsBackToRemovedReviewer(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+
+	a := pr.AssignedReviewers[0]
+	b := mustReassign(t, s, pr.ID, a)
+	if c := mustReassign(t, s, pr.ID, b); c != a {
+		t.Fatalf("replaced %s by %s, want the only candidate left %s", b, c, a)
+	}
+}
+
+func TestReconcileReplacesInactiveReviewers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	gone, kept := pr.AssignedReviewers[0], pr.AssignedReviewers[1]
+	spare := "u2"
+	for _, id := range []string{"u2", "u3", "u4"} {
+		if id != gone && id != kept {
+			spare = id
+		}
+	}
+
+	if _, err := s.SetUserActive(ctx, gone, false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	got, replaced, err := s.ReconcileReviewers(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	// the author and the reviewer still assigned are never picked
+	if len(replaced) != 1 || replaced[0].OldUserID != gone || replaced[0].NewUserID != spare {
+		t.Fatalf("replaced = %+v, want %s -> %s", replaced, gone, spare)
+	}
+	assertReviewers(t, got, kept, spare)
+	if ops := auditOperations(t, s, AuditFilter{Operation: AuditReviewerReassign, EntityID: spare}); len(ops) != 1 {
+		t.Fatalf("reassign audit = %v, want one row", ops)
+	}
+
+	// without an eligible replacement the inactive reviewer is dropped
+	if _, err := s.SetUserActive(ctx, spare, false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	got, replaced, err = s.ReconcileReviewers(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(replaced) != 0 {
+		t.Fatalf("replaced = %+v, want none", replaced)
+	}
+	assertReviewers(t, got, kept)
+	if ops := auditOperations(t, s, AuditFilter{Operation: AuditReviewerReassign, EntityID: pr.ID}); len(ops) != 1 {
+		t.Fatalf("reassign audit = %v, want no row for the dropped reviewer", ops)
+	}
+
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	_, _, err = s.ReconcileReviewers(ctx, pr.ID)
+	assertCode(t, err, CodePRMerged)
+}
+
+func TestConcurrentReassignmentsKeepReviewerSetConsistent(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"),
+		activeMember("u4"), activeMember("u5"), activeMember("u6"))
+
+	for _, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4", "pr-5"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+
+		errs := make(chan error, len(pr.AssignedReviewers))
+		for _, old := range pr.AssignedReviewers {
+			go func() {
+				_, _, err := s.ReassignReviewer(ctx, pr.ID, old)
+				errs <- err
+			}()
+		}
+		for range pr.AssignedReviewers {
+			if err := <-errs; err != nil {
+				t.Fatalf("%s: concurrent reassign: %v", id, err)
+			}
+		}
+
+		got, err := s.GetPullRequest(ctx, pr.ID)
+		if err != nil {
+			t.Fatalf("get %s: %v", id, err)
+		}
+		if len(got.AssignedReviewers) != 2 {
+			t.Fatalf("%s: reviewers = %v, want 2", id, got.AssignedReviewers)
+		}
+		if got.AssignedReviewers[0] == got.AssignedReviewers[1] {
+			t.Fatalf("%s: duplicate reviewer %v", id, got.AssignedReviewers)
+		}
+		for _, r := range got.AssignedReviewers {
+			if r == "u1" || slices.Contains(pr.AssignedReviewers, r) {
+				t.Fatalf("%s: reviewers %v after replacing %v", id, got.AssignedReviewers, pr.AssignedReviewers)
+			}
+		}
+	}
+}
+
+func TestReassignAllForUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-ok", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-merged", Author: "u1"})
+	if _, err := s.MergePullRequest(ctx, "pr-merged"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if _, err := s.AddTeamMembers(ctx, "backend", []models.TeamMember{activeMember("u4")}); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+	// u2, u3 and u4 all review this one, so nobody is left to take over
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-full", Author: "u1", ReviewerCount: 3})
+
+	summary, err := s.ReassignAllForUser(ctx, "u2")
+	if err != nil {
+		t.Fatalf("reassign all: %v", err)
+	}
+	if len(summary.Reassigned) != 1 || summary.Reassigned[0] != (ReassignResult{PullRequestID: "pr-ok", ReplacedBy: "u4"}) {
+		t.Fatalf("reassigned = %+v, want pr-ok -> u4", summary.Reassigned)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0].PullRequestID != "pr-full" || summary.Failed[0].Code != CodeNoCandidate {
+		t.Fatalf("failed = %+v, want pr-full with NO_CANDIDATE", summary.Failed)
+	}
+
+	merged, err := s.GetPullRequest(ctx, "pr-merged")
+	if err != nil {
+		t.Fatalf("get merged PR: %v", err)
+	}
+	assertReviewers(t, merged, "u2", "u3")
+	full, err := s.GetPullRequest(ctx, "pr-full")
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	assertReviewers(t, full, "u2", "u3", "u4")
+
+	_, err = s.ReassignAllForUser(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestReassignTwoOfTwoReviewers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"), activeMember("u5"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	old := pr.AssignedReviewers
+	var spare []string
+	for _, id := range []string{"u2", "u3", "u4", "u5"} {
+		if !slices.Contains(old, id) {
+			spare = append(spare, id)
+		}
+	}
+
+	got, entries, err := s.ReassignReviewers(ctx, pr.ID, old)
+	if err != nil {
+		t.Fatalf("reassign both: %v", err)
+	}
+	// neither replacement may be the other old reviewer or the author
+	assertReviewers(t, got, spare...)
+	if len(entries) != 2 || entries[0].OldUserID != old[0] || entries[1].OldUserID != old[1] ||
+		entries[0].NewUserID == entries[1].NewUserID {
+		t.Fatalf("entries = %+v for %v", entries, old)
+	}
+
+	// one id that is not assigned fails the whole call
+	_, _, err = s.ReassignReviewers(ctx, pr.ID, []string{spare[0], "u1"})
+	assertCode(t, err, CodeNotAssigned)
+	unchanged, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	assertReviewers(t, unchanged, spare...)
+}
+
+func TestReviewerHistoryAfterCreateAndReassign(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	old := pr.AssignedReviewers[0]
+	replacedBy := mustReassign(t, s, pr.ID, old)
+
+	history, err := s.GetReviewerHistory(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	want := []models.ReviewerHistoryEntry{
+		{PullRequestID: pr.ID, UserID: pr.AssignedReviewers[0], Action: models.ReviewerAssigned},
+		{PullRequestID: pr.ID, UserID: pr.AssignedReviewers[1], Action: models.ReviewerAssigned},
+		{PullRequestID: pr.ID, UserID: old, Action: models.ReviewerReplaced},
+		{PullRequestID: pr.ID, UserID: replacedBy, Action: models.ReviewerAssigned},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history = %+v, want %d entries", history, len(want))
+	}
+	for i, entry := range history {
+		if entry.At.IsZero() || entry.At.Location() != time.UTC {
+			t.Fatalf("entry %d at = %v, want a UTC timestamp", i, entry.At)
+		}
+		if i > 0 && entry.At.Before(history[i-1].At) {
+			t.Fatalf("history out of order: %+v", history)
+		}
+		entry.At = time.Time{}
+		// the initial assignments may be recorded in either order
+		if i < 2 && entry.UserID == want[1-i].UserID {
+			entry.UserID = want[i].UserID
+		}
+		if entry != want[i] {
+			t.Fatalf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+
+	_, err = s.GetReviewerHistory(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestTargetedReassignment(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2", "u3")
+	if _, err := s.AddTeamMembers(ctx, "backend", []models.TeamMember{activeMember("u4"), inactiveMember("u5"), activeMember("u6")}); err != nil {
+		t.Fatalf("add members: %v", err)
+	}
+	if _, err := s.SetUserUnavailable(ctx, "u6", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("set unavailable: %v", err)
+	}
+
+	tests := []struct {
+		target  string
+		code    string
+		message string
+	}{
+		{target: "u1", code: CodeInvalidReviewer, message: "author cannot review their own PR"},
+		{target: "u3", code: CodeInvalidReviewer, message: "new reviewer is already assigned to this PR"},
+		{target: "u2", code: CodeInvalidReviewer, message: "new reviewer is already assigned to this PR"},
+		{target: "f1", code: CodeInvalidReviewer, message: "new reviewer is not in the replaced reviewer's team"},
+		{target: "u5", code: CodeInvalidReviewer, message: "new reviewer is inactive"},
+		{target: "u6", code: CodeInvalidReviewer, message: "new reviewer is unavailable"},
+		{target: "missing", code: CodeNotFound, message: "new reviewer not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			_, _, err := s.ReassignReviewersWith(ctx, pr.ID, []string{"u2"}, ReassignOptions{Targets: map[string]string{"u2": tt.target}})
+			assertCode(t, err, tt.code)
+			var appErr *AppError
+			if errors.As(err, &appErr) && appErr.Message != tt.message {
+				t.Fatalf("message = %q, want %q", appErr.Message, tt.message)
+			}
+		})
+	}
+	unchanged, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	assertReviewers(t, unchanged, "u2", "u3")
+
+	got, entries, err := s.ReassignReviewersWith(ctx, pr.ID, []string{"u2"}, ReassignOptions{Targets: map[string]string{"u2": "u4"}})
+	if err != nil {
+		t.Fatalf("targeted reassign: %v", err)
+	}
+	assertReviewers(t, got, "u3", "u4")
+	if len(entries) != 1 || entries[0].OldUserID != "u2" || entries[0].NewUserID != "u4" {
+		t.Fatalf("entries = %+v, want u2 -> u4", entries)
+	}
+}
+
+func TestReassignmentRecordsActor(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("lead"), activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	first := pr.AssignedReviewers[0]
+
+	_, _, err := s.ReassignReviewersWith(ctx, pr.ID, []string{first}, ReassignOptions{ActorID: "ghost"})
+	assertCode(t, err, CodeNotFound)
+	unchanged, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	assertReviewers(t, unchanged, first)
+
+	_, entries, err := s.ReassignReviewersWith(ctx, pr.ID, []string{first}, ReassignOptions{ActorID: "lead"})
+	if err != nil {
+		t.Fatalf("reassign with actor: %v", err)
+	}
+	if len(entries) != 1 || entries[0].ActorID != "lead" {
+		t.Fatalf("entries = %+v, want actor lead", entries)
+	}
+	// without an actor the history stores none
+	mustReassign(t, s, pr.ID, entries[0].NewUserID)
+
+	history, err := s.ReassignmentHistory(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 2 || history[0].ActorID != "lead" || history[1].ActorID != "" {
+		t.Fatalf("history = %+v, want actors lead then none", history)
+	}
+}
+
+// preinsertOnDelete makes every reviewer removal on pr-1 add userID as a
+// reviewer first, so the replacement pick collides with an existing row.
+func preinsertOnDelete(t *testing.T, s *Service, userID string) {
+	t.Helper()
+	mustExec(t, s, `CREATE FUNCTION preinsert_reviewer() RETURNS trigger AS $$
+		BEGIN
+			INSERT INTO pr_reviewers (pull_request_id, user_id)
+			VALUES (OLD.pull_request_id, '`+userID+`') ON CONFLICT DO NOTHING;
+			RETURN OLD;
+		END $$ LANGUAGE plpgsql`)
+	mustExec(t, s, `CREATE TRIGGER preinsert_reviewer AFTER DELETE ON pr_reviewers
+		FOR EACH ROW EXECUTE FUNCTION preinsert_reviewer()`)
+}
+
+func TestReassignPickCollision(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("only candidate taken", func(t *testing.T) {
+		s := newTestService(t)
+		mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+		mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+		mustExec(t, s, `UPDATE pr_reviewers SET user_id = 'u2'`)
+		preinsertOnDelete(t, s, "u3")
+
+		_, _, err := s.ReassignReviewer(ctx, "pr-1", "u2")
+		assertCode(t, err, CodeNoCandidate)
+		pr, err := s.GetPullRequest(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("get PR: %v", err)
+		}
+		assertReviewers(t, pr, "u2")
+	})
+
+	t.Run("alternate picked", func(t *testing.T) {
+		for seed := range 5 {
+			s := NewWithRand(dbtest.Open(t), rand.New(rand.NewSource(int64(seed))))
+			mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+			mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+			mustExec(t, s, `UPDATE pr_reviewers SET user_id = 'u2'`)
+			preinsertOnDelete(t, s, "u3")
+
+			pr, replacedBy, err := s.ReassignReviewer(ctx, "pr-1", "u2")
+			if err != nil {
+				assertCode(t, err, CodeNoCandidate)
+				continue
+			}
+			// u3 slipped in behind the lock, so u4 is the only clean pick
+			if replacedBy != "u4" {
+				t.Fatalf("seed %d: replaced by %s, want u4", seed, replacedBy)
+			}
+			assertReviewers(t, pr, "u3", "u4")
+		}
+	})
+}
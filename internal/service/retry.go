@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxTxAttempts = 3
+	retryBackoff  = 20 * time.Millisecond
+)
+
+// withRetry re-runs fn when it fails with a transient Postgres error. fn must
+// run its own transaction so that every attempt starts from scratch.
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(retryBackoff << (attempt - 1)):
+			}
+		}
+		err = fn()
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01": // serialization_failure, deadlock_detected
+			return true
+		}
+		return pqErr.Code.Class() == "08" // connection_exception
+	}
+	return false
+}
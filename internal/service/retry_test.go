@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// flaky fails its first failures calls with err, then succeeds.
+type flaky struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flaky) run() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func TestWithRetry(t *testing.T) {
+	serialization := &pq.Error{Code: "40001"}
+	tests := []struct {
+		name     string
+		failures int
+		err      error
+		wantErr  bool
+		calls    int
+	}{
+		{name: "fails twice then succeeds", failures: 2, err: serialization, calls: 3},
+		{name: "wrapped deadlock", failures: 2, err: fmt.Errorf("insert pr: %w", &pq.Error{Code: "40P01"}), calls: 3},
+		{name: "bad connection", failures: 1, err: driver.ErrBadConn, calls: 2},
+		{name: "gives up", failures: maxTxAttempts, err: serialization, wantErr: true, calls: maxTxAttempts},
+		{name: "app error", failures: 1, err: newAppError(409, CodePRExists, "exists"), wantErr: true, calls: 1},
+		{name: "unique violation", failures: 1, err: &pq.Error{Code: "23505"}, wantErr: true, calls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &flaky{failures: tt.failures, err: tt.err}
+			err := withRetry(context.Background(), f.run)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if f.calls != tt.calls {
+				t.Fatalf("calls = %d, want %d", f.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f := &flaky{failures: 2, err: &pq.Error{Code: "40001"}}
+	err := withRetry(ctx, f.run)
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || f.calls != 1 {
+		t.Fatalf("err = %v after %d calls, want the first error and no retry", err, f.calls)
+	}
+}
+
+func TestCreatePullRequestRetriesSerializationFailures(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	// the counter lives outside the failing transactions, so it survives
+	// their rollback and lets the third attempt through
+	mustExec(t, s, `CREATE SEQUENCE pr_insert_attempts`)
+	mustExec(t, s, `CREATE FUNCTION fail_twice() RETURNS trigger AS $$
+		BEGIN
+			IF nextval('pr_insert_attempts') <= 2 THEN
+				RAISE EXCEPTION 'simulated conflict' USING ERRCODE = 'serialization_failure';
+			END IF;
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`)
+	mustExec(t, s, `CREATE TRIGGER fail_twice BEFORE INSERT ON pull_requests FOR EACH ROW EXECUTE FUNCTION fail_twice()`)
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2")
+	var attempts int
+	if err := s.db.QueryRow(`SELECT last_value FROM pr_insert_attempts`).Scan(&attempts); err != nil {
+		t.Fatalf("read attempts: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("insert attempts = %d, want 3", attempts)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func withMemberRole(m models.TeamMember, role string) models.TeamMember {
+	m.Role = &role
+	return m
+}
+
+func TestWithRole(t *testing.T) {
+	role := func(r string) sql.NullString { return sql.NullString{String: r, Valid: true} }
+	candidates := []candidate{
+		{ID: "u1", Role: role("frontend")},
+		{ID: "u2", Role: role("backend")},
+		{ID: "u3"},
+		{ID: "u4", Role: role("frontend")},
+	}
+	tests := []struct {
+		role string
+		want []string
+	}{
+		{role: "", want: []string{"u1", "u2", "u3", "u4"}},
+		{role: "frontend", want: []string{"u1", "u4"}},
+		{role: "backend", want: []string{"u2"}},
+		{role: "qa", want: []string{"u1", "u2", "u3", "u4"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, c := range withRole(candidates, tt.role) {
+			got = append(got, c.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("withRole(%q) = %v, want %v", tt.role, got, tt.want)
+		}
+	}
+}
+
+func TestCreatePullRequestByRole(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "web",
+		activeMember("u1"),
+		withMemberRole(activeMember("fe1"), "frontend"),
+		withMemberRole(activeMember("fe2"), "frontend"),
+		withMemberRole(inactiveMember("fe3"), "frontend"),
+		withMemberRole(activeMember("be1"), "backend"),
+		activeMember("u2"),
+	)
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-fe", Author: "u1", Role: "frontend"})
+	assertReviewers(t, pr, "fe1", "fe2")
+
+	// an inactive member of the role does not count as a match
+	mustCreateTeam(t, s, "mobile", activeMember("m1"), activeMember("m2"), withMemberRole(inactiveMember("m3"), "ios"))
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-ios", Author: "m1", Role: "ios"})
+	assertReviewers(t, pr, "m2")
+
+	// nobody with the role: any active member will do
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-qa", Author: "fe1", Role: "qa", ReviewerCount: 4})
+	assertReviewers(t, pr, "u1", "fe2", "be1", "u2")
+
+	team, err := s.GetTeam(ctx, "web", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	for _, m := range team.Members {
+		switch {
+		case m.UserID == "be1" && (m.Role == nil || *m.Role != "backend"):
+			t.Fatalf("be1 role = %v, want backend", m.Role)
+		case m.UserID == "u1" && m.Role != nil:
+			t.Fatalf("u1 role = %q, want none", *m.Role)
+		}
+	}
+}
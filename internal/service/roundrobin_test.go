@@ -0,0 +1,32 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRoundRobinRotation(t *testing.T) {
+	s := newTestService(t)
+	s.SetStrategy(StrategyRoundRobin)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), inactiveMember("u3"), activeMember("u4"), activeMember("u5"))
+
+	var got []string
+	for _, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1", ReviewerCount: 1})
+		got = append(got, pr.AssignedReviewers...)
+	}
+	// u3 is inactive and u1 is the author: the cursor skips both and wraps
+	if want := []string{"u2", "u4", "u5", "u2"}; !slices.Equal(got, want) {
+		t.Fatalf("rotation = %v, want %v", got, want)
+	}
+
+	// the cursor is per team and continues after the last pick
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-5", Author: "u2"})
+	if want := []string{"u4", "u5"}; !slices.Equal(pr.AssignedReviewers, want) {
+		t.Fatalf("reviewers = %v, want %v", pr.AssignedReviewers, want)
+	}
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-6", Author: "u2"})
+	if want := []string{"u1", "u4"}; !slices.Equal(pr.AssignedReviewers, want) {
+		t.Fatalf("reviewers = %v, want %v", pr.AssignedReviewers, want)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func bigTeam(n int, member func(i int, id string) models.TeamMember) []models.TeamMember {
+	members := make([]models.TeamMember, n)
+	for i := range members {
+		members[i] = member(i, fmt.Sprintf("m%03d", i))
+	}
+	return members
+}
+
+func TestSampledCandidatesKeepExclusions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	s.SetSampleInDB(3)
+	// every third member is inactive and m001 is away, m000 is the author
+	excluded := map[string]bool{"m000": true, "m001": true}
+	mustCreateTeam(t, s, "big", bigTeam(30, func(i int, id string) models.TeamMember {
+		if i%3 == 2 {
+			excluded[id] = true
+			return inactiveMember(id)
+		}
+		return activeMember(id)
+	})...)
+	if _, err := s.SetUserUnavailable(ctx, "m001", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("set unavailable: %v", err)
+	}
+
+	for i := range 40 {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: fmt.Sprintf("pr-%d", i), Author: "m000"})
+		if len(pr.AssignedReviewers) != 2 {
+			t.Fatalf("%s reviewers = %v, want 2", pr.ID, pr.AssignedReviewers)
+		}
+		for _, id := range pr.AssignedReviewers {
+			if excluded[id] {
+				t.Fatalf("%s: sampled path assigned excluded member %s", pr.ID, id)
+			}
+		}
+	}
+}
+
+func TestSampleSkippedWhenFiltersApply(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		member func(i int, id string) models.TeamMember
+		input  CreatePRInput
+		setup  func(t *testing.T, s *Service)
+		want   string
+	}{
+		{
+			name: "role",
+			member: func(i int, id string) models.TeamMember {
+				if i == 37 {
+					return withMemberRole(activeMember(id), "ios")
+				}
+				return activeMember(id)
+			},
+			input: CreatePRInput{Role: "ios"},
+			want:  "m037",
+		},
+		{
+			name: "own cap",
+			member: func(i int, id string) models.TeamMember {
+				if i == 23 {
+					return activeMember(id)
+				}
+				return capped(activeMember(id), 0)
+			},
+			want: "m023",
+		},
+		{
+			name:   "diversify",
+			member: func(i int, id string) models.TeamMember { return activeMember(id) },
+			input:  CreatePRInput{Diversify: true},
+			setup: func(t *testing.T, s *Service) {
+				// everyone but m042 reviewed one of the author's last merged PRs
+				for i := range 3 {
+					id := fmt.Sprintf("merged-%d", i)
+					mustCreatePR(t, s, CreatePRInput{ID: id, Author: "m000", ReviewerCount: 1})
+					if _, err := s.MergePullRequest(ctx, id); err != nil {
+						t.Fatalf("merge: %v", err)
+					}
+				}
+				mustExec(t, s, `DELETE FROM pr_reviewers`)
+				mustExec(t, s, `INSERT INTO pr_reviewers (pull_request_id, user_id)
+					SELECT 'merged-0', user_id FROM users WHERE user_id NOT IN ('m000', 'm042')`)
+			},
+			want: "m042",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			s.SetSampleInDB(2)
+			mustCreateTeam(t, s, "big", bigTeam(50, tt.member)...)
+			if tt.setup != nil {
+				tt.setup(t, s)
+			}
+			for i := range 10 {
+				in := tt.input
+				in.ID, in.Author, in.ReviewerCount = fmt.Sprintf("pr-%d", i), "m000", 1
+				pr := mustCreatePR(t, s, in)
+				assertReviewers(t, pr, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkReviewerCandidates(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{0, 5} {
+		name := "full"
+		if size > 0 {
+			name = fmt.Sprintf("sample-%d", size)
+		}
+		b.Run(name, func(b *testing.B) {
+			s := NewWithRand(dbtest.Open(b), rand.New(rand.NewSource(1)))
+			s.SetSampleInDB(size)
+			members := bigTeam(500, func(i int, id string) models.TeamMember { return activeMember(id) })
+			if _, err := s.CreateTeam(ctx, models.Team{TeamName: "big", Members: members}); err != nil {
+				b.Fatalf("create team: %v", err)
+			}
+			tx, err := s.db.BeginTx(ctx, nil)
+			if err != nil {
+				b.Fatalf("begin: %v", err)
+			}
+			defer tx.Rollback()
+
+			for b.Loop() {
+				if _, err := s.reviewerCandidates(ctx, tx, []string{"big"}, "m000", 2, false); err != nil {
+					b.Fatalf("candidates: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package service
+
+import "testing"
+
+func TestSelfReviewIsOptIn(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "solo", activeMember("u1"))
+	mustCreateTeam(t, s, "backend", activeMember("b1"), activeMember("b2"), inactiveMember("b3"))
+
+	// off by default: a lone author gets no reviewers
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-default", Author: "u1"})
+	assertReviewers(t, pr)
+
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-self", Author: "u1", AllowSelfReview: true})
+	assertReviewers(t, pr, "u1")
+
+	// only a fallback: with a teammate available the author is not picked
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-team", Author: "b1", AllowSelfReview: true})
+	assertReviewers(t, pr, "b2")
+}
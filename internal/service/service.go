@@ -5,9 +5,15 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/123jjck/avito-trainee-assignment/internal/models"
 )
 
@@ -15,16 +21,32 @@ const (
 	CodeTeamExists  = "TEAM_EXISTS"
 	CodePRExists    = "PR_EXISTS"
 	CodePRMerged    = "PR_MERGED"
+	CodePRClosed    = "PR_CLOSED"
 	CodeNotAssigned = "NOT_ASSIGNED"
 	CodeNoCandidate = "NO_CANDIDATE"
 	CodeNotFound    = "NOT_FOUND"
+	CodeBadRequest  = "BAD_REQUEST"
+
+	CodeInsufficientApprovals = "INSUFFICIENT_APPROVALS"
+	CodeUsernameExists        = "USERNAME_EXISTS"
+	CodeTeamInUse             = "TEAM_IN_USE"
+	CodeInsufficientReviewers = "INSUFFICIENT_REVIEWERS"
+	CodeChangesRequested      = "CHANGES_REQUESTED"
+	CodeAuthorInactive        = "AUTHOR_INACTIVE"
+	CodeInvalidReviewer       = "INVALID_REVIEWER"
+	CodeAuthorNoTeam          = "AUTHOR_NO_TEAM"
 )
 
 type Stats struct {
-	TotalPRs    int              `json:"total_prs"`
-	OpenPRs     int              `json:"open_prs"`
-	MergedPRs   int              `json:"merged_prs"`
-	Assignments []AssignmentStat `json:"assignments"`
+	TotalPRs                 int              `json:"total_prs"`
+	OpenPRs                  int              `json:"open_prs"`
+	MergedPRs                int              `json:"merged_prs"`
+	ClosedPRs                int              `json:"closed_prs"`
+	AvgMergeSeconds          float64          `json:"avg_merge_seconds"`
+	AvgOpenAssignmentSeconds float64          `json:"avg_open_assignment_seconds"`
+	DistributionStdDev       float64          `json:"distribution_stddev"`
+	DistributionGini         float64          `json:"distribution_gini"`
+	Assignments              []AssignmentStat `json:"assignments"`
 }
 
 type AssignmentStat struct {
@@ -47,19 +69,127 @@ func newAppError(status int, code, msg string) *AppError {
 	return &AppError{Status: status, Code: code, Message: msg}
 }
 
+type Strategy string
+
+const (
+	StrategyRandom     Strategy = "random"
+	StrategyRoundRobin Strategy = "round_robin"
+)
+
 type Service struct {
-	db  *sql.DB
-	rnd *rand.Rand
+	db              *sql.DB
+	rndMu           sync.Mutex // rand.Rand is not safe for concurrent use
+	rnd             *rand.Rand
+	minApprovals    int
+	requireReviewer bool
+	requireActive   bool
+	maxOpenReviews  int
+	strategy        Strategy
+	opTimeout       time.Duration
+	sampleInDB      bool
+	sampleSize      int
 }
 
+const defaultOpTimeout = 5 * time.Second
+
 func New(db *sql.DB) *Service {
+	return NewWithRand(db, nil)
+}
+
+// NewWithRand lets callers inject a seeded source so reviewer selection is
+// reproducible; a nil source falls back to a time-seeded one, as in New.
+func NewWithRand(db *sql.DB, rnd *rand.Rand) *Service {
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
 	return &Service{
-		db:  db,
-		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+		db:            db,
+		rnd:           rnd,
+		strategy:      StrategyRandom,
+		opTimeout:     defaultOpTimeout,
+		requireActive: true,
+	}
+}
+
+func (s *Service) SetMinApprovals(n int) {
+	s.minApprovals = n
+}
+
+func (s *Service) SetRequireReviewer(v bool) {
+	s.requireReviewer = v
+}
+
+// SetRequireActiveAuthor controls whether inactive users (e.g. bots kept
+// inactive so they never review) may open PRs. It is on by default.
+func (s *Service) SetRequireActiveAuthor(v bool) {
+	s.requireActive = v
+}
+
+func (s *Service) SetMaxOpenReviews(n int) {
+	s.maxOpenReviews = n
+}
+
+func (s *Service) SetStrategy(strategy Strategy) {
+	s.strategy = strategy
+}
+
+// SetSampleInDB makes random reviewer selection load at most size random
+// candidates per team lookup instead of every active member, which keeps PR
+// creation cheap in very large teams; zero loads everyone. The sample is
+// drawn by the database, so a seeded source only orders within it, and it is
+// skipped whenever a filter could reject sampled members.
+func (s *Service) SetSampleInDB(size int) {
+	s.sampleInDB = size > 0
+	s.sampleSize = size
+}
+
+// SetOpTimeout bounds every service call, so a stalled client cannot keep a
+// transaction open; zero disables the limit.
+func (s *Service) SetOpTimeout(d time.Duration) {
+	s.opTimeout = d
+}
+
+func (s *Service) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.opTimeout <= 0 {
+		return ctx, func() {}
 	}
+	return context.WithTimeout(ctx, s.opTimeout)
+}
+
+func (s *Service) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.db.PingContext(ctx)
+}
+
+func (s *Service) DBStats() sql.DBStats {
+	return s.db.Stats()
+}
+
+func (s *Service) CountActiveUsers(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE is_active = true`).Scan(&n)
+	return n, err
 }
 
 func (s *Service) CreateTeam(ctx context.Context, team models.Team) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var created models.Team
+	err := withRetry(ctx, func() error {
+		var err error
+		created, err = s.createTeam(ctx, team)
+		return err
+	})
+	return created, err
+}
+
+func (s *Service) createTeam(ctx context.Context, team models.Team) (models.Team, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return models.Team{}, err
@@ -75,43 +205,143 @@ func (s *Service) CreateTeam(ctx context.Context, team models.Team) (models.Team
 		return models.Team{}, err
 	}
 
-	if _, err := tx.ExecContext(ctx, "INSERT INTO teams(team_name) VALUES ($1)", team.TeamName); err != nil {
+	if team.DefaultReviewerCount <= 0 {
+		team.DefaultReviewerCount = defaultReviewerCount
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO teams(team_name, default_reviewer_count) VALUES ($1, $2)",
+		team.TeamName, team.DefaultReviewerCount,
+	); err != nil {
 		return models.Team{}, fmt.Errorf("insert team: %w", err)
 	}
 
-	for _, member := range team.Members {
+	if err := upsertMembers(ctx, tx, team.TeamName, team.Members); err != nil {
+		return models.Team{}, err
+	}
+	ids := []string{team.TeamName}
+	for _, m := range team.Members {
+		ids = append(ids, m.UserID)
+	}
+	if err := insertAudit(ctx, tx, AuditTeamCreate, ids, team); err != nil {
+		return models.Team{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Team{}, err
+	}
+	team.CountMembers()
+	team.IsArchived = false
+	return team, nil
+}
+
+func (s *Service) AddTeamMembers(ctx context.Context, teamName string, members []models.TeamMember) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Team{}, err
+	}
+	defer tx.Rollback()
+
+	var exists string
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", teamName).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Team{}, newAppError(404, CodeNotFound, "team not found")
+	}
+	if err != nil {
+		return models.Team{}, err
+	}
+
+	if err := upsertMembers(ctx, tx, teamName, members); err != nil {
+		return models.Team{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Team{}, err
+	}
+	return s.GetTeam(ctx, teamName, GetTeamOptions{IncludeArchived: true})
+}
+
+// MemberUpdate lists the fields of one member to change; nil fields are left
+// as they are.
+type MemberUpdate struct {
+	UserID   string
+	Username *string
+	IsActive *bool
+}
+
+// UpdateTeamMember changes only the given fields of an existing member of the
+// team; unlike AddTeamMembers it never creates users.
+func (s *Service) UpdateTeamMember(ctx context.Context, teamName string, member MemberUpdate) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	user, err := scanUser(s.db.QueryRowContext(ctx,
+		`UPDATE users SET username = COALESCE($3, username), is_active = COALESCE($4, is_active)
+		 WHERE user_id = $1 AND team_name = $2
+		 RETURNING `+userColumns,
+		member.UserID, teamName, member.Username, member.IsActive,
+	))
+	if isUniqueViolation(err, "users_team_username_key") {
+		return models.User{}, newAppError(409, CodeUsernameExists, fmt.Sprintf("username %s already exists in team %s", *member.Username, teamName))
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Code == CodeNotFound {
+		return models.User{}, newAppError(404, CodeNotFound, "member not found in team")
+	}
+	return user, err
+}
+
+func upsertMembers(ctx context.Context, tx *sql.Tx, teamName string, members []models.TeamMember) error {
+	for _, member := range members {
 		_, err := tx.ExecContext(
 			ctx,
-			`INSERT INTO users (user_id, username, team_name, is_active)
-			 VALUES ($1, $2, $3, $4)
+			`INSERT INTO users (user_id, username, team_name, is_active, max_open_reviews, role)
+			 VALUES ($1, $2, $3, $4, $5, $6)
 			 ON CONFLICT (user_id)
 			 DO UPDATE SET username = EXCLUDED.username,
 			               team_name = EXCLUDED.team_name,
-			               is_active = EXCLUDED.is_active`,
-			member.UserID, member.Username, team.TeamName, member.IsActive,
+			               is_active = EXCLUDED.is_active,
+			               max_open_reviews = EXCLUDED.max_open_reviews,
+			               role = EXCLUDED.role`,
+			member.UserID, member.Username, teamName, member.IsActive, member.MaxOpenReviews, member.Role,
 		)
+		if isUniqueViolation(err, "users_team_username_key") {
+			return newAppError(409, CodeUsernameExists, fmt.Sprintf("username %s already exists in team %s", member.Username, teamName))
+		}
 		if err != nil {
-			return models.Team{}, fmt.Errorf("upsert user %s: %w", member.UserID, err)
+			return fmt.Errorf("upsert user %s: %w", member.UserID, err)
 		}
 	}
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return models.Team{}, err
-	}
-	return team, nil
+type GetTeamOptions struct {
+	// IncludeArchived returns archived teams instead of reporting them as not
+	// found.
+	IncludeArchived bool
+	// ActiveOnly leaves inactive users out of Members and MemberCount;
+	// ActiveCount and TotalCount still cover the whole team.
+	ActiveOnly bool
 }
 
-func (s *Service) GetTeam(ctx context.Context, teamName string) (models.Team, error) {
+func (s *Service) GetTeam(ctx context.Context, teamName string, opts GetTeamOptions) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	var team models.Team
-	err := s.db.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", teamName).Scan(&team.TeamName)
-	if errors.Is(err, sql.ErrNoRows) {
+	err := s.db.QueryRowContext(ctx,
+		"SELECT team_name, is_archived, default_reviewer_count FROM teams WHERE team_name = $1", teamName,
+	).Scan(&team.TeamName, &team.IsArchived, &team.DefaultReviewerCount)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && team.IsArchived && !opts.IncludeArchived) {
 		return models.Team{}, newAppError(404, CodeNotFound, "team not found")
 	}
 	if err != nil {
 		return models.Team{}, err
 	}
 
-	rows, err := s.db.QueryContext(ctx, `SELECT user_id, username, is_active FROM users WHERE team_name = $1 ORDER BY user_id`, teamName)
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, username, is_active, max_open_reviews, role FROM users WHERE team_name = $1 ORDER BY user_id`, teamName)
 	if err != nil {
 		return models.Team{}, err
 	}
@@ -119,312 +349,1923 @@ func (s *Service) GetTeam(ctx context.Context, teamName string) (models.Team, er
 
 	for rows.Next() {
 		var m models.TeamMember
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		var maxOpen sql.NullInt64
+		var role sql.NullString
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &maxOpen, &role); err != nil {
 			return models.Team{}, err
 		}
+		m.MaxOpenReviews = nullIntPtr(maxOpen)
+		if role.Valid {
+			m.Role = &role.String
+		}
 		team.Members = append(team.Members, m)
 	}
 	if rows.Err() != nil {
 		return models.Team{}, rows.Err()
 	}
-	return team, nil
-}
-
-func (s *Service) SetUserActive(ctx context.Context, userID string, isActive bool) (models.User, error) {
-	var u models.User
-	err := s.db.QueryRowContext(
-		ctx,
-		`UPDATE users SET is_active = $2 WHERE user_id = $1
-		 RETURNING user_id, username, team_name, is_active`,
-		userID, isActive,
-	).Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
-	if errors.Is(err, sql.ErrNoRows) {
-		return models.User{}, newAppError(404, CodeNotFound, "user not found")
-	}
-	if err != nil {
-		return models.User{}, err
+	team.CountMembers()
+	if opts.ActiveOnly {
+		active := team.Members[:0]
+		for _, m := range team.Members {
+			if m.IsActive {
+				active = append(active, m)
+			}
+		}
+		team.Members = active
+		team.MemberCount = len(active)
 	}
-	return u, nil
+	return team, nil
 }
 
-type CreatePRInput struct {
-	ID     string
-	Name   string
-	Author string
-}
+func (s *Service) RenameTeam(ctx context.Context, oldName, newName string) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-func (s *Service) CreatePullRequest(ctx context.Context, input CreatePRInput) (models.PullRequest, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, err
 	}
 	defer tx.Rollback()
 
 	var exists string
-	if err := tx.QueryRowContext(ctx, "SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", input.ID).Scan(&exists); err == nil {
-		return models.PullRequest{}, newAppError(409, CodePRExists, "PR id already exists")
-	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return models.PullRequest{}, err
-	}
-
-	var author models.User
-	err = tx.QueryRowContext(ctx,
-		`SELECT user_id, username, team_name, is_active FROM users WHERE user_id = $1`,
-		input.Author,
-	).Scan(&author.UserID, &author.Username, &author.TeamName, &author.IsActive)
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", oldName).Scan(&exists)
 	if errors.Is(err, sql.ErrNoRows) {
-		return models.PullRequest{}, newAppError(404, CodeNotFound, "author not found")
+		return models.Team{}, newAppError(404, CodeNotFound, "team not found")
 	}
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, err
 	}
 
-	var createdAt time.Time
-	if err := tx.QueryRowContext(ctx,
-		`INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status)
-		 VALUES ($1, $2, $3, $4)
-		 RETURNING created_at`,
-		input.ID, input.Name, input.Author, models.StatusOpen,
-	).Scan(&createdAt); err != nil {
-		return models.PullRequest{}, fmt.Errorf("insert pr: %w", err)
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", newName).Scan(&exists)
+	if err == nil {
+		return models.Team{}, newAppError(400, CodeTeamExists, "team_name already exists")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Team{}, err
 	}
 
-	candidates, err := s.activeTeamMembers(ctx, tx, author.TeamName, input.Author)
+	// Members and the round-robin cursor follow via ON UPDATE CASCADE. Links
+	// are re-inserted because team_a < team_b may flip with the new name.
+	linked, err := s.linkedTeams(ctx, tx, oldName)
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, err
 	}
-	assignments := pickRandom(s.rnd, candidates, 2)
-	for _, reviewer := range assignments {
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM team_links WHERE team_a = $1 OR team_b = $1", oldName,
+	); err != nil {
+		return models.Team{}, fmt.Errorf("unlink team: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE teams SET team_name = $2 WHERE team_name = $1", oldName, newName); err != nil {
+		return models.Team{}, fmt.Errorf("rename team: %w", err)
+	}
+	for _, other := range linked {
+		a, b := newName, other
+		if a > b {
+			a, b = b, a
+		}
 		if _, err := tx.ExecContext(ctx,
-			`INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES ($1, $2)`,
-			input.ID, reviewer,
+			`INSERT INTO team_links (team_a, team_b) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			a, b,
 		); err != nil {
-			return models.PullRequest{}, fmt.Errorf("assign reviewer %s: %w", reviewer, err)
+			return models.Team{}, fmt.Errorf("relink team: %w", err)
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, err
 	}
-
-	return models.PullRequest{
-		ID:                input.ID,
-		Name:              input.Name,
-		AuthorID:          input.Author,
-		Status:            models.StatusOpen,
-		AssignedReviewers: assignments,
-		CreatedAt:         &createdAt,
-	}, nil
+	return s.GetTeam(ctx, newName, GetTeamOptions{IncludeArchived: true})
 }
 
-func (s *Service) MergePullRequest(ctx context.Context, prID string) (models.PullRequest, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+// ArchiveTeam keeps the team and its history but takes its members out of
+// reviewer selection. Archiving an archived team is a no-op.
+func (s *Service) ArchiveTeam(ctx context.Context, teamName string) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE teams SET is_archived = true WHERE team_name = $1", teamName)
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, fmt.Errorf("archive team: %w", err)
 	}
-	defer tx.Rollback()
-
-	var pr models.PullRequest
-	var createdAt time.Time
-	var mergedAt sql.NullTime
-	err = tx.QueryRowContext(ctx,
-		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
-		 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`,
-		prID,
-	).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &createdAt, &mergedAt)
-	if errors.Is(err, sql.ErrNoRows) {
-		return models.PullRequest{}, newAppError(404, CodeNotFound, "pull request not found")
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Team{}, err
+	} else if n == 0 {
+		return models.Team{}, newAppError(404, CodeNotFound, "team not found")
 	}
+	return s.GetTeam(ctx, teamName, GetTeamOptions{IncludeArchived: true})
+}
+
+// SetTeamReviewerCount changes how many reviewers PRs of the team's members
+// get when they do not ask for a specific number.
+func (s *Service) SetTeamReviewerCount(ctx context.Context, teamName string, count int) (models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE teams SET default_reviewer_count = $2 WHERE team_name = $1", teamName, count)
 	if err != nil {
-		return models.PullRequest{}, err
+		return models.Team{}, fmt.Errorf("set team reviewer count: %w", err)
 	}
-	pr.CreatedAt = &createdAt
-	if mergedAt.Valid {
-		pr.MergedAt = &mergedAt.Time
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Team{}, err
+	} else if n == 0 {
+		return models.Team{}, newAppError(404, CodeNotFound, "team not found")
 	}
+	return s.GetTeam(ctx, teamName, GetTeamOptions{IncludeArchived: true})
+}
 
-	if pr.Status != models.StatusMerged {
-		var updated time.Time
-		err = tx.QueryRowContext(ctx,
-			`UPDATE pull_requests SET status = $2, merged_at = COALESCE(merged_at, now())
-			 WHERE pull_request_id = $1
-			 RETURNING merged_at`,
-			prID, models.StatusMerged,
-		).Scan(&updated)
+func (s *Service) LinkTeams(ctx context.Context, a, b string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if a > b {
+		a, b = b, a
+	}
+	for _, name := range []string{a, b} {
+		var exists string
+		err := s.db.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", name).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return newAppError(404, CodeNotFound, fmt.Sprintf("team %s not found", name))
+		}
 		if err != nil {
-			return models.PullRequest{}, err
+			return err
 		}
-		pr.Status = models.StatusMerged
-		pr.MergedAt = &updated
-	}
-
-	pr.AssignedReviewers, err = s.loadReviewers(ctx, tx, prID)
-	if err != nil {
-		return models.PullRequest{}, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return models.PullRequest{}, err
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO team_links (team_a, team_b) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		a, b,
+	); err != nil {
+		return fmt.Errorf("link teams: %w", err)
 	}
-	return pr, nil
+	return nil
 }
 
-func (s *Service) ReassignReviewer(ctx context.Context, prID, oldUserID string) (models.PullRequest, string, error) {
+// DeleteTeam removes a team together with its members. Only CLOSED pull
+// requests may reference the members; those are removed along with them.
+func (s *Service) DeleteTeam(ctx context.Context, teamName string) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return models.PullRequest{}, "", err
+		return 0, err
 	}
 	defer tx.Rollback()
 
-	var pr models.PullRequest
-	var createdAt time.Time
-	var mergedAt sql.NullTime
-	err = tx.QueryRowContext(ctx,
-		`SELECT pull_request_id, pull_request_name, author_id, status, created_at, merged_at
-		 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`,
-		prID,
-	).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &createdAt, &mergedAt)
+	var exists string
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", teamName).Scan(&exists)
 	if errors.Is(err, sql.ErrNoRows) {
-		return models.PullRequest{}, "", newAppError(404, CodeNotFound, "pull request not found")
+		return 0, newAppError(404, CodeNotFound, "team not found")
 	}
 	if err != nil {
-		return models.PullRequest{}, "", err
-	}
-	pr.CreatedAt = &createdAt
-	if mergedAt.Valid {
-		pr.MergedAt = &mergedAt.Time
-	}
-
-	if pr.Status == models.StatusMerged {
-		return models.PullRequest{}, "", newAppError(409, CodePRMerged, "cannot reassign on merged PR")
+		return 0, err
 	}
 
-	assigned, err := s.loadReviewers(ctx, tx, prID)
-	if err != nil {
-		return models.PullRequest{}, "", err
-	}
-	if !contains(assigned, oldUserID) {
-		return models.PullRequest{}, "", newAppError(409, CodeNotAssigned, "reviewer is not assigned to this PR")
+	var blocking int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(DISTINCT pr.pull_request_id)
+		 FROM pull_requests pr
+		 LEFT JOIN pr_reviewers r ON r.pull_request_id = pr.pull_request_id
+		 WHERE pr.status <> $2
+		   AND (pr.author_id IN (SELECT user_id FROM users WHERE team_name = $1)
+		        OR r.user_id IN (SELECT user_id FROM users WHERE team_name = $1))`,
+		teamName, models.StatusClosed,
+	).Scan(&blocking); err != nil {
+		return 0, err
+	}
+	if blocking > 0 {
+		return 0, newAppError(409, CodeTeamInUse,
+			fmt.Sprintf("team members are referenced by %d pull requests that are not CLOSED", blocking))
 	}
 
-	var user models.User
-	err = tx.QueryRowContext(ctx,
-		`SELECT user_id, username, team_name, is_active FROM users WHERE user_id = $1`,
-		oldUserID,
-	).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
-	if errors.Is(err, sql.ErrNoRows) {
-		return models.PullRequest{}, "", newAppError(404, CodeNotFound, "user not found")
-	}
-	if err != nil {
-		return models.PullRequest{}, "", err
+	stmts := []string{
+		`DELETE FROM pull_requests WHERE author_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+		`DELETE FROM pr_reviewers WHERE user_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+		`DELETE FROM pr_approvals WHERE user_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+		`DELETE FROM pr_change_requests WHERE user_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+		`DELETE FROM reassignment_log
+		 WHERE old_user_id IN (SELECT user_id FROM users WHERE team_name = $1)
+		    OR new_user_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+		`DELETE FROM pr_reviewer_history WHERE user_id IN (SELECT user_id FROM users WHERE team_name = $1)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt, teamName); err != nil {
+			return 0, fmt.Errorf("delete team references: %w", err)
+		}
 	}
-
-	candidates, err := s.activeTeamMembers(ctx, tx, user.TeamName, oldUserID)
+	rows, err := tx.QueryContext(ctx, "DELETE FROM users WHERE team_name = $1 RETURNING user_id", teamName)
 	if err != nil {
-		return models.PullRequest{}, "", err
-	}
-	assignedSet := make(map[string]struct{}, len(assigned))
-	for _, id := range assigned {
-		assignedSet[id] = struct{}{}
+		return 0, fmt.Errorf("delete team members: %w", err)
 	}
-	filtered := make([]string, 0, len(candidates))
-	for _, id := range candidates {
-		if _, already := assignedSet[id]; already {
-			continue
-		}
-		if id == pr.AuthorID {
-			continue // avoid self-review on reassignment as well
+	defer rows.Close()
+	var members []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
 		}
-		filtered = append(filtered, id)
-	}
-
-	if len(filtered) == 0 {
-		return models.PullRequest{}, "", newAppError(409, CodeNoCandidate, "no active replacement candidate in team")
+		members = append(members, id)
 	}
-	newReviewer := filtered[s.rnd.Intn(len(filtered))]
-
-	if _, err := tx.ExecContext(ctx,
-		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`,
-		prID, oldUserID,
-	); err != nil {
-		return models.PullRequest{}, "", err
+	if err := rows.Err(); err != nil {
+		return 0, err
 	}
-	if _, err := tx.ExecContext(ctx,
-		`INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES ($1, $2)`,
-		prID, newReviewer,
-	); err != nil {
-		return models.PullRequest{}, "", err
+	rows.Close()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE team_name = $1", teamName); err != nil {
+		return 0, fmt.Errorf("delete team: %w", err)
 	}
-
-	pr.AssignedReviewers, err = s.loadReviewers(ctx, tx, prID)
-	if err != nil {
-		return models.PullRequest{}, "", err
+	if err := insertAudit(ctx, tx, AuditTeamDelete, append([]string{teamName}, members...), map[string]any{
+		"team_name": teamName,
+		"members":   members,
+	}); err != nil {
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return models.PullRequest{}, "", err
+		return 0, err
 	}
-
-	return pr, newReviewer, nil
+	return len(members), nil
 }
 
-func (s *Service) ListUserReviews(ctx context.Context, userID string) ([]models.PullRequestShort, error) {
-	var exists string
-	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1", userID).Scan(&exists)
+const userColumns = "user_id, username, team_name, is_active, max_open_reviews, unavailable_until"
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var u models.User
+	var maxOpen sql.NullInt64
+	var unavailableUntil sql.NullTime
+	err := row.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive, &maxOpen, &unavailableUntil)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, newAppError(404, CodeNotFound, "user not found")
+		return models.User{}, newAppError(404, CodeNotFound, "user not found")
 	}
 	if err != nil {
-		return nil, err
+		return models.User{}, err
 	}
+	u.MaxOpenReviews = nullIntPtr(maxOpen)
+	u.UnavailableUntil = utcPtr(unavailableUntil)
+	return u, nil
+}
 
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
+func (s *Service) SetUserActive(ctx context.Context, userID string, isActive bool) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRowContext(ctx,
+		`UPDATE users SET is_active = $2 WHERE user_id = $1 RETURNING `+userColumns,
+		userID, isActive,
+	))
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := insertAudit(ctx, tx, AuditUserSetActive, []string{user.UserID}, map[string]any{
+		"is_active": isActive,
+	}); err != nil {
+		return models.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (s *Service) SetUserMaxOpenReviews(ctx context.Context, userID string, maxOpen *int) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(ctx,
+		`UPDATE users SET max_open_reviews = $2 WHERE user_id = $1 RETURNING `+userColumns,
+		userID, maxOpen,
+	))
+}
+
+func (s *Service) SetUserUnavailable(ctx context.Context, userID string, until time.Time) (models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(ctx,
+		`UPDATE users SET unavailable_until = $2 WHERE user_id = $1 RETURNING `+userColumns,
+		userID, nullTime(until),
+	))
+}
+
+const defaultReviewerCount = 2
+
+// diversifyWindow is how many of the author's latest merged PRs Diversify
+// looks at when deciding who reviewed them recently.
+const diversifyWindow = 3
+
+type CreatePRInput struct {
+	ID            string
+	Name          string
+	Author        string
+	ReviewerCount int
+	// MinReviewers, when positive, makes creation fail instead of assigning
+	// fewer reviewers than this.
+	MinReviewers int
+	CrossTeam    bool
+	// AllowSelfReview lets the author review their own PR when nobody else
+	// can, so PRs in single-member teams are not left without a reviewer.
+	AllowSelfReview bool
+	// Role, when set, prefers reviewers with that role and falls back to any
+	// active member if none of them is available.
+	Role string
+	// Diversify skips reviewers of the author's recent merged PRs while other
+	// candidates remain, and only then tops up from them.
+	Diversify bool
+	// AvoidRepeatReviewers skips reviewers of the author's previous PR while
+	// other candidates remain, the same way Diversify does.
+	AvoidRepeatReviewers bool
+}
+
+// CreatePullRequest never assigns the author as a reviewer: if the author is the
+// only active member of the team, the PR is created with no reviewers, unless
+// the service requires a reviewer, in which case NO_CANDIDATE is returned.
+// With AllowSelfReview the author is assigned instead in that case.
+func (s *Service) CreatePullRequest(ctx context.Context, input CreatePRInput) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var pr models.PullRequest
+	err := withRetry(ctx, func() error {
+		var err error
+		pr, err = s.createPullRequest(ctx, input)
+		return err
+	})
+	return pr, err
+}
+
+func (s *Service) createPullRequest(ctx context.Context, input CreatePRInput) (models.PullRequest, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	var exists string
+	if err := tx.QueryRowContext(ctx, "SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", input.ID).Scan(&exists); err == nil {
+		return models.PullRequest{}, newAppError(409, CodePRExists, "PR id already exists")
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return models.PullRequest{}, err
+	}
+
+	var author models.User
+	var teamExists bool
+	var teamReviewerCount int
+	err = tx.QueryRowContext(ctx,
+		`SELECT u.user_id, u.username, COALESCE(u.team_name, ''), u.is_active, t.team_name IS NOT NULL,
+		        COALESCE(t.default_reviewer_count, $2)
+		 FROM users u
+		 LEFT JOIN teams t ON t.team_name = u.team_name
+		 WHERE u.user_id = $1`,
+		input.Author, defaultReviewerCount,
+	).Scan(&author.UserID, &author.Username, &author.TeamName, &author.IsActive, &teamExists, &teamReviewerCount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PullRequest{}, newAppError(404, CodeNotFound, "author not found")
+	}
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	// The foreign key keeps team_name valid today; this guards against
+	// teamless users so they get a clear error instead of a PR nobody reviews.
+	if author.TeamName == "" {
+		return models.PullRequest{}, newAppError(409, CodeAuthorNoTeam, "author does not belong to any team")
+	}
+	if !teamExists {
+		return models.PullRequest{}, newAppError(409, CodeAuthorNoTeam, fmt.Sprintf("author's team %s does not exist", author.TeamName))
+	}
+	if !author.IsActive && s.requireActive {
+		return models.PullRequest{}, newAppError(409, CodeAuthorInactive, "author is inactive")
+	}
+
+	reviewerCount := input.ReviewerCount
+	if reviewerCount <= 0 {
+		reviewerCount = teamReviewerCount
+	}
+	// Checked against the team default too, which the handler cannot see.
+	if input.MinReviewers > reviewerCount {
+		return models.PullRequest{}, newAppError(400, CodeBadRequest,
+			fmt.Sprintf("min_reviewers %d exceeds the %d reviewers to assign", input.MinReviewers, reviewerCount))
+	}
+
+	var createdAt, updatedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING created_at, updated_at`,
+		input.ID, input.Name, input.Author, models.StatusOpen,
+	).Scan(&createdAt, &updatedAt); err != nil {
+		return models.PullRequest{}, fmt.Errorf("insert pr: %w", err)
+	}
+
+	filtered := input.Role != "" || input.Diversify || input.AvoidRepeatReviewers
+	candidates, err := s.reviewerCandidates(ctx, tx, []string{author.TeamName}, input.Author, reviewerCount, filtered)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	candidates = withRole(candidates, input.Role)
+	var recent []candidate
+	if input.Diversify {
+		candidates, recent, err = s.splitRecentReviewers(ctx, tx, input.Author, candidates)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+	}
+	if input.AvoidRepeatReviewers {
+		var previous []candidate
+		candidates, previous, err = s.splitPreviousReviewers(ctx, tx, input.Author, input.ID, candidates)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+		recent = append(recent, previous...)
+	}
+	var assignments []string
+	if s.strategy == StrategyRoundRobin {
+		assignments, err = s.pickRoundRobin(ctx, tx, author.TeamName, s.withinCapacity(candidates), reviewerCount)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+	} else {
+		assignments = s.pickRandom(s.withinCapacity(candidates), reviewerCount)
+	}
+	if len(recent) > 0 && len(assignments) < reviewerCount {
+		assignments = append(assignments, s.pickRandom(s.withinCapacity(recent), reviewerCount-len(assignments))...)
+	}
+	if input.CrossTeam && len(assignments) < reviewerCount {
+		linked, err := s.linkedTeams(ctx, tx, author.TeamName)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+		if len(linked) > 0 {
+			extra, err := s.reviewerCandidates(ctx, tx, linked, input.Author, reviewerCount, filtered)
+			if err != nil {
+				return models.PullRequest{}, err
+			}
+			extra = withRole(extra, input.Role)
+			assignments = append(assignments, s.pickRandom(s.withinCapacity(extra), reviewerCount-len(assignments))...)
+		}
+	}
+	if len(assignments) == 0 && input.AllowSelfReview {
+		everyone, err := s.activeTeamMembers(ctx, tx, []string{author.TeamName}, "")
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+		for _, c := range everyone {
+			if c.ID == author.UserID {
+				assignments = s.withinCapacity([]candidate{c})
+			}
+		}
+	}
+	if len(assignments) == 0 && s.requireReviewer {
+		return models.PullRequest{}, newAppError(409, CodeNoCandidate, "no active reviewer candidate in team")
+	}
+	if input.MinReviewers > 0 && len(assignments) < input.MinReviewers {
+		return models.PullRequest{}, newAppError(409, CodeInsufficientReviewers,
+			fmt.Sprintf("only %d reviewer candidates available, %d required", len(assignments), input.MinReviewers))
+	}
+	for _, reviewer := range assignments {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES ($1, $2)`,
+			input.ID, reviewer,
+		); err != nil {
+			return models.PullRequest{}, fmt.Errorf("assign reviewer %s: %w", reviewer, err)
+		}
+		if err := recordReviewerHistory(ctx, tx, input.ID, reviewer, models.ReviewerAssigned); err != nil {
+			return models.PullRequest{}, err
+		}
+	}
+
+	pr := models.PullRequest{
+		ID:                input.ID,
+		Name:              input.Name,
+		AuthorID:          input.Author,
+		AuthorUsername:    author.Username,
+		Status:            models.StatusOpen,
+		AssignedReviewers: assignments,
+		Approvals:         []string{},
+		CreatedAt:         utcPtr(createdAt),
+		UpdatedAt:         utcPtr(updatedAt),
+	}
+	if err := s.fillReviewers(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, err
+	}
+	if err := insertEvent(ctx, tx, EventPRCreated, pr); err != nil {
+		return models.PullRequest{}, err
+	}
+	if err := insertAudit(ctx, tx, AuditPRCreate, append([]string{pr.ID, pr.AuthorID}, pr.AssignedReviewers...), pr); err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+func (s *Service) GetPullRequest(ctx context.Context, prID string) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.loadPullRequest(ctx, tx, prID, "")
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if err := s.fillReviewers(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, err
+	}
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+func (s *Service) MergePullRequest(ctx context.Context, prID string) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var pr models.PullRequest
+	err := withRetry(ctx, func() error {
+		var err error
+		pr, err = s.mergePullRequest(ctx, prID)
+		return err
+	})
+	return pr, err
+}
+
+func (s *Service) mergePullRequest(ctx context.Context, prID string) (models.PullRequest, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if pr.Status == models.StatusClosed {
+		return models.PullRequest{}, newAppError(409, CodePRClosed, "cannot merge closed PR")
+	}
+
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+
+	merged := false
+	if pr.Status != models.StatusMerged {
+		if len(pr.ChangesRequested) > 0 {
+			return models.PullRequest{}, newAppError(409, CodeChangesRequested,
+				fmt.Sprintf("changes requested by %s", strings.Join(pr.ChangesRequested, ", ")))
+		}
+		if len(pr.Approvals) < s.minApprovals {
+			return models.PullRequest{}, newAppError(409, CodeInsufficientApprovals,
+				fmt.Sprintf("PR has %d of %d required approvals", len(pr.Approvals), s.minApprovals))
+		}
+
+		var mergedAt, updatedAt sql.NullTime
+		err = tx.QueryRowContext(ctx,
+			`UPDATE pull_requests SET status = $2, merged_at = COALESCE(merged_at, now()), updated_at = now()
+			 WHERE pull_request_id = $1
+			 RETURNING merged_at, updated_at`,
+			prID, models.StatusMerged,
+		).Scan(&mergedAt, &updatedAt)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+		pr.Status = models.StatusMerged
+		pr.MergedAt = utcPtr(mergedAt)
+		pr.UpdatedAt = utcPtr(updatedAt)
+		merged = true
+	}
+
+	err = s.fillReviewers(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if merged {
+		if err := insertEvent(ctx, tx, EventPRMerged, pr); err != nil {
+			return models.PullRequest{}, err
+		}
+		if err := insertAudit(ctx, tx, AuditPRMerge, []string{pr.ID}, pr); err != nil {
+			return models.PullRequest{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+func (s *Service) ClosePullRequest(ctx context.Context, prID string) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if pr.Status == models.StatusMerged {
+		return models.PullRequest{}, newAppError(409, CodePRMerged, "cannot close merged PR")
+	}
+
+	closed := false
+	if pr.Status != models.StatusClosed {
+		var closedAt, updatedAt sql.NullTime
+		err = tx.QueryRowContext(ctx,
+			`UPDATE pull_requests SET status = $2, closed_at = COALESCE(closed_at, now()), updated_at = now()
+			 WHERE pull_request_id = $1
+			 RETURNING closed_at, updated_at`,
+			prID, models.StatusClosed,
+		).Scan(&closedAt, &updatedAt)
+		if err != nil {
+			return models.PullRequest{}, err
+		}
+		pr.Status = models.StatusClosed
+		pr.ClosedAt = utcPtr(closedAt)
+		pr.UpdatedAt = utcPtr(updatedAt)
+		closed = true
+	}
+
+	err = s.fillReviewers(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if closed {
+		if err := insertAudit(ctx, tx, AuditPRClose, []string{pr.ID}, pr); err != nil {
+			return models.PullRequest{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+// DeletePullRequest removes a PR with its reviewers, approvals and
+// reassignment history. Without force only OPEN PRs can be deleted.
+func (s *Service) DeletePullRequest(ctx context.Context, prID string, force bool) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if !force {
+		if pr.Status == models.StatusMerged {
+			return models.PullRequest{}, newAppError(409, CodePRMerged, "cannot delete merged PR without force")
+		}
+		if pr.Status == models.StatusClosed {
+			return models.PullRequest{}, newAppError(409, CodePRClosed, "cannot delete closed PR without force")
+		}
+	}
+	if err := s.fillReviewers(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, err
+	}
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pull_requests WHERE pull_request_id = $1", prID); err != nil {
+		return models.PullRequest{}, fmt.Errorf("delete pull request: %w", err)
+	}
+	if err := insertAudit(ctx, tx, AuditPRDelete, []string{pr.ID}, map[string]any{
+		"force":        force,
+		"pull_request": pr,
+	}); err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+func (s *Service) ApprovePullRequest(ctx context.Context, prID, userID string) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if pr.Status == models.StatusMerged {
+		return models.PullRequest{}, newAppError(409, CodePRMerged, "cannot approve merged PR")
+	}
+	if pr.Status == models.StatusClosed {
+		return models.PullRequest{}, newAppError(409, CodePRClosed, "cannot approve closed PR")
+	}
+
+	err = s.fillReviewers(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if !contains(pr.AssignedReviewers, userID) {
+		return models.PullRequest{}, newAppError(409, CodeNotAssigned, "reviewer is not assigned to this PR")
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO pr_approvals (pull_request_id, user_id) VALUES ($1, $2)
+		 ON CONFLICT (pull_request_id, user_id) DO NOTHING`,
+		prID, userID,
+	); err != nil {
+		return models.PullRequest{}, fmt.Errorf("insert approval: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM pr_change_requests WHERE pull_request_id = $1 AND user_id = $2`,
+		prID, userID,
+	); err != nil {
+		return models.PullRequest{}, err
+	}
+	if err := touchPullRequest(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, err
+	}
+
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+// RequestChanges blocks merge until the same reviewer approves; it also
+// withdraws that reviewer's earlier approval.
+func (s *Service) RequestChanges(ctx context.Context, prID, userID string) (models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if pr.Status == models.StatusMerged {
+		return models.PullRequest{}, newAppError(409, CodePRMerged, "cannot request changes on merged PR")
+	}
+	if pr.Status == models.StatusClosed {
+		return models.PullRequest{}, newAppError(409, CodePRClosed, "cannot request changes on closed PR")
+	}
+
+	err = s.fillReviewers(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	if !contains(pr.AssignedReviewers, userID) {
+		return models.PullRequest{}, newAppError(409, CodeNotAssigned, "reviewer is not assigned to this PR")
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO pr_change_requests (pull_request_id, user_id) VALUES ($1, $2)
+		 ON CONFLICT (pull_request_id, user_id) DO UPDATE SET requested_at = now()`,
+		prID, userID,
+	); err != nil {
+		return models.PullRequest{}, fmt.Errorf("insert change request: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM pr_approvals WHERE pull_request_id = $1 AND user_id = $2`,
+		prID, userID,
+	); err != nil {
+		return models.PullRequest{}, err
+	}
+	if err := touchPullRequest(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, err
+	}
+
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, err
+	}
+	return pr, nil
+}
+
+func (s *Service) ReassignReviewer(ctx context.Context, prID, oldUserID string) (models.PullRequest, string, error) {
+	pr, entries, err := s.ReassignReviewers(ctx, prID, []string{oldUserID})
+	if err != nil {
+		return models.PullRequest{}, "", err
+	}
+	return pr, entries[0].NewUserID, nil
+}
+
+// ReassignReviewers replaces several reviewers of one PR in a single
+// transaction; a replacement is never one of the reviewers still assigned,
+// including the ones being replaced.
+func (s *Service) ReassignReviewers(ctx context.Context, prID string, oldUserIDs []string) (models.PullRequest, []models.Reassignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		pr      models.PullRequest
+		entries []models.Reassignment
+	)
+	err := withRetry(ctx, func() error {
+		var err error
+		pr, entries, err = s.reassignReviewers(ctx, prID, oldUserIDs, ReassignOptions{})
+		return err
+	})
+	return pr, entries, err
+}
+
+// ReassignOptions tunes ReassignReviewersWith; the zero value picks random
+// replacements and records no actor.
+type ReassignOptions struct {
+	// Targets maps an old reviewer to a hand-picked replacement, who must be
+	// an active, available member of the old reviewer's team and neither the
+	// author nor already assigned. The review limit is not enforced for them.
+	Targets map[string]string
+	// ActorID is the user who asked for the reassignment; it is stored in the
+	// reassignment history.
+	ActorID string
+}
+
+func (s *Service) ReassignReviewersWith(ctx context.Context, prID string, oldUserIDs []string, opts ReassignOptions) (models.PullRequest, []models.Reassignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		pr      models.PullRequest
+		entries []models.Reassignment
+	)
+	err := withRetry(ctx, func() error {
+		var err error
+		pr, entries, err = s.reassignReviewers(ctx, prID, oldUserIDs, opts)
+		return err
+	})
+	return pr, entries, err
+}
+
+func (s *Service) reassignReviewers(ctx context.Context, prID string, oldUserIDs []string, opts ReassignOptions) (models.PullRequest, []models.Reassignment, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+
+	if pr.Status == models.StatusMerged {
+		return models.PullRequest{}, nil, newAppError(409, CodePRMerged, "cannot reassign on merged PR")
+	}
+	if pr.Status == models.StatusClosed {
+		return models.PullRequest{}, nil, newAppError(409, CodePRClosed, "cannot reassign on closed PR")
+	}
+
+	assigned, err := s.loadReviewers(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	for _, oldUserID := range oldUserIDs {
+		if !contains(assigned, oldUserID) {
+			return models.PullRequest{}, nil, newAppError(409, CodeNotAssigned, "reviewer is not assigned to this PR")
+		}
+	}
+	if opts.ActorID != "" {
+		var exists string
+		err := tx.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1", opts.ActorID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PullRequest{}, nil, newAppError(404, CodeNotFound, "actor not found")
+		}
+		if err != nil {
+			return models.PullRequest{}, nil, err
+		}
+	}
+
+	entries := make([]models.Reassignment, 0, len(oldUserIDs))
+	for _, oldUserID := range oldUserIDs {
+		entry, err := s.replaceReviewer(ctx, tx, pr, assigned, oldUserID, opts.Targets[oldUserID], opts.ActorID)
+		if err != nil {
+			return models.PullRequest{}, nil, err
+		}
+		assigned = append(assigned, entry.NewUserID)
+		entries = append(entries, entry)
+	}
+	if err := touchPullRequest(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, nil, err
+	}
+
+	err = s.fillReviewers(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	for _, entry := range entries {
+		if err := insertEvent(ctx, tx, EventReviewerReassigned, map[string]any{
+			"pr":          pr,
+			"old_user_id": entry.OldUserID,
+			"replaced_by": entry.NewUserID,
+		}); err != nil {
+			return models.PullRequest{}, nil, err
+		}
+		if err := insertAudit(ctx, tx, AuditReviewerReassign, []string{pr.ID, entry.OldUserID, entry.NewUserID}, map[string]any{
+			"old_user_id": entry.OldUserID,
+			"new_user_id": entry.NewUserID,
+			"actor_id":    entry.ActorID,
+		}); err != nil {
+			return models.PullRequest{}, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, nil, err
+	}
+
+	return pr, entries, nil
+}
+
+type ReassignResult struct {
+	PullRequestID string `json:"pull_request_id"`
+	ReplacedBy    string `json:"replaced_by"`
+}
+
+type ReassignFailure struct {
+	PullRequestID string `json:"pull_request_id"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+}
+
+type ReassignSummary struct {
+	Reassigned []ReassignResult  `json:"reassigned"`
+	Failed     []ReassignFailure `json:"failed"`
+}
+
+// ReassignAllForUser moves userID off every open PR they review, one PR per
+// transaction. PRs that cannot be reassigned (e.g. NO_CANDIDATE) are reported
+// in Failed and keep the user as reviewer. On any other error the summary
+// still lists the PRs handled before it.
+func (s *Service) ReassignAllForUser(ctx context.Context, userID string) (ReassignSummary, error) {
+	summary := ReassignSummary{Reassigned: []ReassignResult{}, Failed: []ReassignFailure{}}
+	prIDs, err := s.openReviewsOf(ctx, userID)
+	if err != nil {
+		return summary, err
+	}
+
+	// Each reassignment applies the op timeout on its own: a reviewer with
+	// many open PRs must not fail halfway just because the batch is long.
+	for _, prID := range prIDs {
+		_, replacedBy, err := s.ReassignReviewer(ctx, prID, userID)
+		var appErr *AppError
+		if errors.As(err, &appErr) {
+			summary.Failed = append(summary.Failed, ReassignFailure{
+				PullRequestID: prID,
+				Code:          appErr.Code,
+				Message:       appErr.Message,
+			})
+			continue
+		}
+		if err != nil {
+			return summary, err
+		}
+		summary.Reassigned = append(summary.Reassigned, ReassignResult{PullRequestID: prID, ReplacedBy: replacedBy})
+	}
+	return summary, nil
+}
+
+// openReviewsOf lists the OPEN PRs userID reviews, oldest first.
+func (s *Service) openReviewsOf(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1", userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newAppError(404, CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pr.pull_request_id
+		 FROM pull_requests pr
+		 JOIN pr_reviewers r ON r.pull_request_id = pr.pull_request_id
+		 WHERE r.user_id = $1 AND pr.status = $2
+		 ORDER BY pr.created_at, pr.pull_request_id`,
+		userID, models.StatusOpen,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var prIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		prIDs = append(prIDs, id)
+	}
+	return prIDs, rows.Err()
+}
+
+// ReconcileReviewers replaces reviewers of an open PR who have been
+// deactivated since assignment. Inactive reviewers without an eligible
+// replacement are dropped.
+func (s *Service) ReconcileReviewers(ctx context.Context, prID string) (models.PullRequest, []models.Reassignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		pr       models.PullRequest
+		replaced []models.Reassignment
+	)
+	err := withRetry(ctx, func() error {
+		var err error
+		pr, replaced, err = s.reconcileReviewers(ctx, prID)
+		return err
+	})
+	return pr, replaced, err
+}
+
+func (s *Service) reconcileReviewers(ctx context.Context, prID string) (models.PullRequest, []models.Reassignment, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	defer tx.Rollback()
+
+	pr, err := s.lockPullRequest(ctx, tx, prID)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	if pr.Status == models.StatusMerged {
+		return models.PullRequest{}, nil, newAppError(409, CodePRMerged, "cannot reconcile merged PR")
+	}
+	if pr.Status == models.StatusClosed {
+		return models.PullRequest{}, nil, newAppError(409, CodePRClosed, "cannot reconcile closed PR")
+	}
+
+	if err := s.fillReviewers(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	assigned := append([]string(nil), pr.AssignedReviewers...)
+	replaced := []models.Reassignment{}
+	changed := false
+	for _, reviewer := range pr.ReviewersDetailed {
+		if reviewer.IsActive {
+			continue
+		}
+		changed = true
+		entry, err := s.replaceReviewer(ctx, tx, pr, assigned, reviewer.UserID, "", "")
+		var appErr *AppError
+		if errors.As(err, &appErr) && appErr.Code == CodeNoCandidate {
+			if err := s.dropReviewer(ctx, tx, pr.ID, reviewer.UserID); err != nil {
+				return models.PullRequest{}, nil, err
+			}
+			if err := recordReviewerHistory(ctx, tx, pr.ID, reviewer.UserID, models.ReviewerRemoved); err != nil {
+				return models.PullRequest{}, nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return models.PullRequest{}, nil, err
+		}
+		assigned = append(assigned, entry.NewUserID)
+		replaced = append(replaced, entry)
+	}
+	if changed {
+		if err := touchPullRequest(ctx, tx, &pr); err != nil {
+			return models.PullRequest{}, nil, err
+		}
+	}
+
+	if err := s.fillReviewers(ctx, tx, &pr); err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	err = s.loadReviewState(ctx, tx, &pr)
+	if err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	for _, r := range replaced {
+		if err := insertEvent(ctx, tx, EventReviewerReassigned, map[string]any{
+			"pr":          pr,
+			"old_user_id": r.OldUserID,
+			"replaced_by": r.NewUserID,
+		}); err != nil {
+			return models.PullRequest{}, nil, err
+		}
+		if err := insertAudit(ctx, tx, AuditReviewerReassign, []string{pr.ID, r.OldUserID, r.NewUserID}, map[string]any{
+			"old_user_id": r.OldUserID,
+			"new_user_id": r.NewUserID,
+			"actor_id":    r.ActorID,
+		}); err != nil {
+			return models.PullRequest{}, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PullRequest{}, nil, err
+	}
+	return pr, replaced, nil
+}
+
+func (s *Service) dropReviewer(ctx context.Context, tx *sql.Tx, prID, userID string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM pr_reviewers WHERE pull_request_id = $1 AND user_id = $2`,
+		prID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM pr_approvals WHERE pull_request_id = $1 AND user_id = $2`,
+		prID, userID,
+	); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`DELETE FROM pr_change_requests WHERE pull_request_id = $1 AND user_id = $2`,
+		prID, userID,
+	)
+	return err
+}
+
+// replaceReviewer swaps oldUserID for an active member of their team who is
+// neither the author nor already assigned, and records the reassignment.
+func (s *Service) replaceReviewer(ctx context.Context, tx *sql.Tx, pr models.PullRequest, assigned []string, oldUserID, target, actorID string) (models.Reassignment, error) {
+	var user models.User
+	err := tx.QueryRowContext(ctx,
+		`SELECT user_id, username, team_name, is_active FROM users WHERE user_id = $1`,
+		oldUserID,
+	).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Reassignment{}, newAppError(404, CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return models.Reassignment{}, err
+	}
+
+	candidates, err := s.activeTeamMembers(ctx, tx, []string{user.TeamName}, oldUserID)
+	if err != nil {
+		return models.Reassignment{}, err
+	}
+	assignedSet := make(map[string]struct{}, len(assigned))
+	for _, id := range assigned {
+		assignedSet[id] = struct{}{}
+	}
+	filtered := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if _, already := assignedSet[c.ID]; already {
+			continue
+		}
+		if c.ID == pr.AuthorID {
+			continue // avoid self-review on reassignment as well
+		}
+		filtered = append(filtered, c)
+	}
+
+	var newReviewer string
+	var pool []string
+	pick := func() string {
+		s.rndMu.Lock()
+		defer s.rndMu.Unlock()
+		return pool[s.rnd.Intn(len(pool))]
+	}
+	if target != "" {
+		if err := s.checkTarget(ctx, tx, pr, user, assignedSet, filtered, target); err != nil {
+			return models.Reassignment{}, err
+		}
+		newReviewer = target
+	} else {
+		if len(filtered) == 0 {
+			return models.Reassignment{}, newAppError(409, CodeNoCandidate, "no active replacement candidate in team")
+		}
+		removed, err := s.removedReviewers(ctx, tx, pr.ID)
+		if err != nil {
+			return models.Reassignment{}, err
+		}
+		pool = s.withinCapacity(withoutRemoved(filtered, removed))
+		newReviewer = pick()
+	}
+
+	if err := s.dropReviewer(ctx, tx, pr.ID, oldUserID); err != nil {
+		return models.Reassignment{}, err
+	}
+	// Defensive guard: assigned was read under the PR row lock and filtered
+	// leaves all of it out, so the pick should never be on the PR yet. Should
+	// a row appear behind that lock anyway (a direct write, or a future path
+	// that skips lockPullRequest), try another pick instead of failing on the
+	// primary key with a 500.
+	for attempt := 1; ; attempt++ {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			pr.ID, newReviewer,
+		)
+		if err != nil {
+			return models.Reassignment{}, err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return models.Reassignment{}, err
+		} else if n > 0 {
+			break
+		}
+		if target != "" {
+			return models.Reassignment{}, newAppError(409, CodeInvalidReviewer, "new reviewer is already assigned to this PR")
+		}
+		pool = slices.DeleteFunc(pool, func(id string) bool { return id == newReviewer })
+		if len(pool) == 0 || attempt >= maxReviewerPicks {
+			return models.Reassignment{}, newAppError(409, CodeNoCandidate, "no active replacement candidate in team")
+		}
+		newReviewer = pick()
+	}
+	entry := models.Reassignment{PullRequestID: pr.ID, OldUserID: oldUserID, NewUserID: newReviewer, ActorID: actorID}
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO reassignment_log (pull_request_id, old_user_id, new_user_id, actor_id) VALUES ($1, $2, $3, NULLIF($4, ''))
+		 RETURNING created_at`,
+		pr.ID, oldUserID, newReviewer, actorID,
+	).Scan(&entry.CreatedAt); err != nil {
+		return models.Reassignment{}, fmt.Errorf("log reassignment: %w", err)
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC()
+	if err := recordReviewerHistory(ctx, tx, pr.ID, oldUserID, models.ReviewerReplaced); err != nil {
+		return models.Reassignment{}, err
+	}
+	if err := recordReviewerHistory(ctx, tx, pr.ID, newReviewer, models.ReviewerAssigned); err != nil {
+		return models.Reassignment{}, err
+	}
+
+	return entry, nil
+}
+
+// maxReviewerPicks bounds how many replacement picks replaceReviewer tries
+// when the picked user turns out to be assigned already.
+const maxReviewerPicks = 3
+
+func recordReviewerHistory(ctx context.Context, tx *sql.Tx, prID, userID, action string) error {
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO pr_reviewer_history (pull_request_id, user_id, action) VALUES ($1, $2, $3)`,
+		prID, userID, action,
+	); err != nil {
+		return fmt.Errorf("record reviewer history: %w", err)
+	}
+	return nil
+}
+
+// GetReviewerHistory returns every reviewer assignment, removal and
+// replacement on the PR in the order they happened.
+func (s *Service) GetReviewerHistory(ctx context.Context, prID string) ([]models.ReviewerHistoryEntry, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newAppError(404, CodeNotFound, "pull request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pull_request_id, user_id, action, at
+		 FROM pr_reviewer_history
+		 WHERE pull_request_id = $1
+		 ORDER BY id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.ReviewerHistoryEntry{}
+	for rows.Next() {
+		var entry models.ReviewerHistoryEntry
+		if err := rows.Scan(&entry.PullRequestID, &entry.UserID, &entry.Action, &entry.At); err != nil {
+			return nil, err
+		}
+		entry.At = entry.At.UTC()
+		history = append(history, entry)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return history, nil
+}
+
+// checkTarget explains why a hand-picked replacement is not among the
+// eligible candidates, if it is not.
+func (s *Service) checkTarget(ctx context.Context, tx *sql.Tx, pr models.PullRequest, old models.User, assigned map[string]struct{}, eligible []candidate, target string) error {
+	for _, c := range eligible {
+		if c.ID == target {
+			return nil
+		}
+	}
+	var teamName string
+	var isActive bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT team_name, is_active FROM users WHERE user_id = $1`, target,
+	).Scan(&teamName, &isActive)
+	if errors.Is(err, sql.ErrNoRows) {
+		return newAppError(404, CodeNotFound, "new reviewer not found")
+	}
+	if err != nil {
+		return err
+	}
+	_, already := assigned[target]
+	switch {
+	case target == pr.AuthorID:
+		return newAppError(409, CodeInvalidReviewer, "author cannot review their own PR")
+	case already || target == old.UserID:
+		return newAppError(409, CodeInvalidReviewer, "new reviewer is already assigned to this PR")
+	case teamName != old.TeamName:
+		return newAppError(409, CodeInvalidReviewer, "new reviewer is not in the replaced reviewer's team")
+	case !isActive:
+		return newAppError(409, CodeInvalidReviewer, "new reviewer is inactive")
+	default:
+		return newAppError(409, CodeInvalidReviewer, "new reviewer is unavailable")
+	}
+}
+
+func (s *Service) ReassignmentHistory(ctx context.Context, prID string) ([]models.Reassignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT pull_request_id FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newAppError(404, CodeNotFound, "pull request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pull_request_id, old_user_id, new_user_id, COALESCE(actor_id, ''), created_at
+		 FROM reassignment_log
+		 WHERE pull_request_id = $1
+		 ORDER BY id`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.Reassignment{}
+	for rows.Next() {
+		var entry models.Reassignment
+		if err := rows.Scan(&entry.PullRequestID, &entry.OldUserID, &entry.NewUserID, &entry.ActorID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC()
+		history = append(history, entry)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return history, nil
+}
+
+func (s *Service) ListUserReviews(ctx context.Context, userID string, limit, offset int) ([]models.PullRequestShort, int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1", userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, newAppError(404, CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pr_reviewers WHERE user_id = $1`, userID,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, a.username, pr.status
+		 FROM pull_requests pr
+		 JOIN pr_reviewers r ON pr.pull_request_id = r.pull_request_id
+		 JOIN users a ON a.user_id = pr.author_id
+		 WHERE r.user_id = $1
+		 ORDER BY pr.created_at DESC, pr.pull_request_id DESC
+		 LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var result []models.PullRequestShort
+	for rows.Next() {
+		var pr models.PullRequestShort
+		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.AuthorUsername, &pr.Status); err != nil {
+			return nil, 0, err
+		}
+		result = append(result, pr)
+	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+	return result, total, nil
+}
+
+// ListUserReviewsAfter pages through the user's reviews by keyset instead of
+// offset, so deep pages stay cheap. A nil cursor starts from the newest PR;
+// the returned cursor is nil on the last page.
+func (s *Service) ListUserReviewsAfter(ctx context.Context, userID string, cursor *ReviewCursor, limit int) ([]models.PullRequestShort, *ReviewCursor, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id = $1", userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, newAppError(404, CodeNotFound, "user not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var afterTime sql.NullTime
+	var afterID string
+	if cursor != nil {
+		afterTime = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		afterID = cursor.PullRequestID
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, a.username, pr.status, pr.created_at
+		 FROM pull_requests pr
+		 JOIN pr_reviewers r ON pr.pull_request_id = r.pull_request_id
+		 JOIN users a ON a.user_id = pr.author_id
+		 WHERE r.user_id = $1
+		   AND ($2::timestamptz IS NULL OR (pr.created_at, pr.pull_request_id) < ($2, $3))
+		 ORDER BY pr.created_at DESC, pr.pull_request_id DESC
+		 LIMIT $4`, userID, afterTime, afterID, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	result := []models.PullRequestShort{}
+	for rows.Next() {
+		var pr models.PullRequestShort
+		var createdAt time.Time
+		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.AuthorUsername, &pr.Status, &createdAt); err != nil {
+			return nil, nil, err
+		}
+		createdAt = createdAt.UTC()
+		pr.CreatedAt = &createdAt
+		result = append(result, pr)
+	}
+	if rows.Err() != nil {
+		return nil, nil, rows.Err()
+	}
+	if len(result) <= limit {
+		return result, nil, nil
+	}
+	result = result[:limit]
+	last := result[limit-1]
+	return result, &ReviewCursor{CreatedAt: *last.CreatedAt, PullRequestID: last.ID}, nil
+}
+
+type PRSort string
+
+const (
+	SortCreatedAt PRSort = "created_at"
+	SortMergedAt  PRSort = "merged_at"
+)
+
+// PRFilter narrows ListPullRequests; empty fields match everything.
+type PRFilter struct {
+	Status   string
+	AuthorID string
+	TeamName string
+	Sort     PRSort
+	Limit    int
+	Offset   int
+}
+
+func (s *Service) ListPullRequests(ctx context.Context, filter PRFilter) ([]models.PullRequestShort, int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	order := "pr.created_at DESC, pr.pull_request_id DESC"
+	if filter.Sort == SortMergedAt {
+		order = "pr.merged_at DESC NULLS LAST, pr.pull_request_id DESC"
+	}
+
+	const where = `WHERE ($1 = '' OR pr.status = $1)
+		   AND ($2 = '' OR pr.author_id = $2)
+		   AND ($3 = '' OR a.team_name = $3)`
+
+	var total int
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pull_requests pr
+		 JOIN users a ON a.user_id = pr.author_id
+		 `+where,
+		filter.Status, filter.AuthorID, filter.TeamName,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, a.username, pr.status,
+		        pr.created_at, pr.merged_at
 		 FROM pull_requests pr
-		 JOIN pr_reviewers r ON pr.pull_request_id = r.pull_request_id
-		 WHERE r.user_id = $1
-		 ORDER BY pr.created_at DESC`, userID)
+		 JOIN users a ON a.user_id = pr.author_id
+		 `+where+`
+		 ORDER BY `+order+`
+		 LIMIT $4 OFFSET $5`,
+		filter.Status, filter.AuthorID, filter.TeamName, filter.Limit, filter.Offset,
+	)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var result []models.PullRequestShort
+	result := []models.PullRequestShort{}
 	for rows.Next() {
 		var pr models.PullRequestShort
-		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status); err != nil {
-			return nil, err
+		var createdAt, mergedAt sql.NullTime
+		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.AuthorUsername, &pr.Status, &createdAt, &mergedAt); err != nil {
+			return nil, 0, err
 		}
+		pr.CreatedAt = utcPtr(createdAt)
+		pr.MergedAt = utcPtr(mergedAt)
 		result = append(result, pr)
 	}
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+	return result, total, nil
+}
+
+type candidate struct {
+	ID             string
+	OpenReviews    int
+	MaxOpenReviews sql.NullInt64
+	Role           sql.NullString
+}
+
+// withRole keeps the candidates with the given role, or all of them when the
+// role is empty or nobody has it.
+func withRole(candidates []candidate, role string) []candidate {
+	if role == "" {
+		return candidates
+	}
+	var matched []candidate
+	for _, c := range candidates {
+		if c.Role.Valid && c.Role.String == role {
+			matched = append(matched, c)
+		}
+	}
+	if len(matched) == 0 {
+		return candidates
+	}
+	return matched
+}
+
+func (c candidate) atCapacity(limit int) bool {
+	if c.MaxOpenReviews.Valid && int64(c.OpenReviews) >= c.MaxOpenReviews.Int64 {
+		return true
+	}
+	return limit > 0 && c.OpenReviews >= limit
+}
+
+func (s *Service) activeTeamMembers(ctx context.Context, tx *sql.Tx, teamNames []string, excludedID string) ([]candidate, error) {
+	return s.queryTeamMembers(ctx, tx, teamNames, excludedID, 0)
+}
+
+// reviewerCandidates is activeTeamMembers for picking new reviewers: with
+// SetSampleInDB and the random strategy it returns a random sample of at
+// least want candidates. Round-robin needs the full ordered list.
+//
+// The sample is drawn before the filters applied in Go, which could leave it
+// empty while eligible members sit outside it. So the full list is loaded
+// instead when filtered is set (role, diversify, avoid-repeat), when the
+// service-wide review cap is on, or when a sampled member is at their own cap.
+func (s *Service) reviewerCandidates(ctx context.Context, tx *sql.Tx, teamNames []string, excludedID string, want int, filtered bool) ([]candidate, error) {
+	if !s.sampleInDB || s.strategy != StrategyRandom || filtered || s.maxOpenReviews > 0 {
+		return s.activeTeamMembers(ctx, tx, teamNames, excludedID)
+	}
+	sample, err := s.queryTeamMembers(ctx, tx, teamNames, excludedID, max(s.sampleSize, want))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range sample {
+		if c.atCapacity(0) {
+			return s.activeTeamMembers(ctx, tx, teamNames, excludedID)
+		}
+	}
+	return sample, nil
+}
+
+// queryTeamMembers returns every eligible member ordered by user_id, or at
+// most sample random ones when sample is positive.
+func (s *Service) queryTeamMembers(ctx context.Context, tx *sql.Tx, teamNames []string, excludedID string, sample int) ([]candidate, error) {
+	order := "ORDER BY u.user_id"
+	args := []any{pq.Array(teamNames), excludedID}
+	if sample > 0 {
+		order = "ORDER BY random() LIMIT $3"
+		args = append(args, sample)
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT u.user_id, u.max_open_reviews, u.role,
+		        (SELECT COUNT(*) FROM pr_reviewers r
+		         JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		         WHERE r.user_id = u.user_id AND p.status = 'OPEN') AS open_reviews
+		 FROM users u
+		 JOIN teams t ON t.team_name = u.team_name
+		 WHERE u.team_name = ANY($1) AND u.is_active = true AND u.user_id <> $2
+		   AND NOT t.is_archived
+		   AND (u.unavailable_until IS NULL OR u.unavailable_until <= now())
+		 `+order,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.ID, &c.MaxOpenReviews, &c.Role, &c.OpenReviews); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
-	return result, nil
+	return candidates, nil
+}
+
+// splitRecentReviewers separates candidates who reviewed any of the author's
+// last diversifyWindow merged PRs from the rest.
+func (s *Service) splitRecentReviewers(ctx context.Context, tx *sql.Tx, authorID string, candidates []candidate) (fresh, recent []candidate, err error) {
+	return splitReviewedBy(ctx, tx, candidates,
+		`SELECT DISTINCT r.user_id
+		 FROM pr_reviewers r
+		 WHERE r.pull_request_id IN (
+		     SELECT pull_request_id FROM pull_requests
+		     WHERE author_id = $1 AND status = 'MERGED'
+		     ORDER BY merged_at DESC, pull_request_id DESC
+		     LIMIT $2)`,
+		authorID, diversifyWindow,
+	)
+}
+
+// splitPreviousReviewers separates candidates who review the author's most
+// recently created PR other than prID from the rest.
+func (s *Service) splitPreviousReviewers(ctx context.Context, tx *sql.Tx, authorID, prID string, candidates []candidate) (fresh, previous []candidate, err error) {
+	return splitReviewedBy(ctx, tx, candidates,
+		`SELECT r.user_id
+		 FROM pr_reviewers r
+		 WHERE r.pull_request_id = (
+		     SELECT pull_request_id FROM pull_requests
+		     WHERE author_id = $1 AND pull_request_id <> $2
+		     ORDER BY created_at DESC, pull_request_id DESC
+		     LIMIT 1)`,
+		authorID, prID,
+	)
+}
+
+// splitReviewedBy partitions candidates by whether their id is among the
+// user ids returned by query.
+func splitReviewedBy(ctx context.Context, tx *sql.Tx, candidates []candidate, query string, args ...any) (fresh, reviewers []candidate, err error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	reviewed := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		reviewed[id] = true
+	}
+	if rows.Err() != nil {
+		return nil, nil, rows.Err()
+	}
+	for _, c := range candidates {
+		if reviewed[c.ID] {
+			reviewers = append(reviewers, c)
+		} else {
+			fresh = append(fresh, c)
+		}
+	}
+	return fresh, reviewers, nil
+}
+
+func (s *Service) linkedTeams(ctx context.Context, tx *sql.Tx, teamName string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT team_b FROM team_links WHERE team_a = $1
+		 UNION
+		 SELECT team_a FROM team_links WHERE team_b = $1
+		 ORDER BY 1`,
+		teamName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		teams = append(teams, name)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return teams, nil
 }
 
-func (s *Service) activeTeamMembers(ctx context.Context, tx *sql.Tx, teamName, excludedID string) ([]string, error) {
+func (s *Service) removedReviewers(ctx context.Context, tx *sql.Tx, prID string) (map[string]struct{}, error) {
 	rows, err := tx.QueryContext(ctx,
-		`SELECT user_id FROM users WHERE team_name = $1 AND is_active = true AND user_id <> $2`,
-		teamName, excludedID,
+		`SELECT DISTINCT old_user_id FROM reassignment_log WHERE pull_request_id = $1`,
+		prID,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var ids []string
+	removed := make(map[string]struct{})
 	for rows.Next() {
 		var id string
 		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		ids = append(ids, id)
+		removed[id] = struct{}{}
 	}
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
-	return ids, nil
+	return removed, nil
+}
+
+// withoutRemoved skips reviewers already taken off the PR to avoid ping-pong,
+// unless nobody else is left.
+func withoutRemoved(candidates []candidate, removed map[string]struct{}) []candidate {
+	fresh := make([]candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if _, ok := removed[c.ID]; !ok {
+			fresh = append(fresh, c)
+		}
+	}
+	if len(fresh) == 0 {
+		return candidates
+	}
+	return fresh
+}
+
+// withinCapacity drops candidates that reached their own max_open_reviews cap
+// or the service-wide limit. When nobody is left below the cap, the
+// least-loaded candidates are returned instead.
+func (s *Service) withinCapacity(candidates []candidate) []string {
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if !c.atCapacity(s.maxOpenReviews) {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) > 0 || len(candidates) == 0 {
+		return ids
+	}
+	minLoad := candidates[0].OpenReviews
+	for _, c := range candidates[1:] {
+		minLoad = min(minLoad, c.OpenReviews)
+	}
+	for _, c := range candidates {
+		if c.OpenReviews == minLoad {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func (s *Service) lockPullRequest(ctx context.Context, tx *sql.Tx, prID string) (models.PullRequest, error) {
+	return s.loadPullRequest(ctx, tx, prID, "FOR UPDATE OF pr")
+}
+
+func (s *Service) loadPullRequest(ctx context.Context, tx *sql.Tx, prID, lock string) (models.PullRequest, error) {
+	var pr models.PullRequest
+	var createdAt, mergedAt, closedAt, updatedAt sql.NullTime
+	err := tx.QueryRowContext(ctx,
+		`SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, u.username,
+		        pr.status, pr.created_at, pr.merged_at, pr.closed_at, pr.updated_at
+		 FROM pull_requests pr
+		 JOIN users u ON u.user_id = pr.author_id
+		 WHERE pr.pull_request_id = $1
+		 `+lock,
+		prID,
+	).Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.AuthorUsername, &pr.Status, &createdAt, &mergedAt, &closedAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PullRequest{}, newAppError(404, CodeNotFound, "pull request not found")
+	}
+	if err != nil {
+		return models.PullRequest{}, err
+	}
+	pr.CreatedAt = utcPtr(createdAt)
+	pr.MergedAt = utcPtr(mergedAt)
+	pr.ClosedAt = utcPtr(closedAt)
+	pr.UpdatedAt = utcPtr(updatedAt)
+	return pr, nil
+}
+
+func touchPullRequest(ctx context.Context, tx *sql.Tx, pr *models.PullRequest) error {
+	var updatedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx,
+		`UPDATE pull_requests SET updated_at = now() WHERE pull_request_id = $1 RETURNING updated_at`,
+		pr.ID,
+	).Scan(&updatedAt); err != nil {
+		return fmt.Errorf("touch pull request: %w", err)
+	}
+	pr.UpdatedAt = utcPtr(updatedAt)
+	return nil
+}
+
+func (s *Service) fillReviewers(ctx context.Context, tx *sql.Tx, pr *models.PullRequest) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT u.user_id, u.username, u.is_active, r.assigned_at
+		 FROM pr_reviewers r
+		 JOIN users u ON u.user_id = r.user_id
+		 WHERE r.pull_request_id = $1
+		 ORDER BY u.user_id`,
+		pr.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	pr.AssignedReviewers = []string{}
+	pr.ReviewersDetailed = []models.Reviewer{}
+	for rows.Next() {
+		var m models.Reviewer
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.AssignedAt); err != nil {
+			return err
+		}
+		m.AssignedAt = m.AssignedAt.UTC()
+		pr.AssignedReviewers = append(pr.AssignedReviewers, m.UserID)
+		pr.ReviewersDetailed = append(pr.ReviewersDetailed, m)
+	}
+	return rows.Err()
 }
 
 func (s *Service) loadReviewers(ctx context.Context, tx *sql.Tx, prID string) ([]string, error) {
@@ -451,55 +2292,342 @@ func (s *Service) loadReviewers(ctx context.Context, tx *sql.Tx, prID string) ([
 	return reviewers, nil
 }
 
-func (s *Service) Stats(ctx context.Context) (Stats, error) {
+func (s *Service) loadReviewState(ctx context.Context, tx *sql.Tx, pr *models.PullRequest) error {
+	var err error
+	pr.Approvals, err = s.loadApprovals(ctx, tx, pr.ID)
+	if err != nil {
+		return err
+	}
+	pr.ChangesRequested, err = s.loadChangeRequests(ctx, tx, pr.ID)
+	return err
+}
+
+func (s *Service) loadChangeRequests(ctx context.Context, tx *sql.Tx, prID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT user_id FROM pr_change_requests WHERE pull_request_id = $1 ORDER BY user_id`,
+		prID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requested := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		requested = append(requested, id)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return requested, nil
+}
+
+func (s *Service) loadApprovals(ctx context.Context, tx *sql.Tx, prID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT user_id FROM pr_approvals WHERE pull_request_id = $1 ORDER BY user_id`,
+		prID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, id)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return approvals, nil
+}
+
+type AssignmentSort string
+
+const (
+	SortCountDesc AssignmentSort = "count_desc"
+	SortCountAsc  AssignmentSort = "count_asc"
+	SortUsername  AssignmentSort = "username"
+)
+
+var assignmentOrder = map[AssignmentSort]string{
+	SortCountDesc: "cnt DESC, u.user_id",
+	SortCountAsc:  "cnt ASC, u.user_id",
+	SortUsername:  "u.username, u.user_id",
+}
+
+// StatsQuery narrows Stats to PRs created in [From, To] (zero bounds are
+// open) and pages the Assignments list; a zero Limit returns every user.
+// The distribution metrics always cover all users, not just the page.
+type StatsQuery struct {
+	From   time.Time
+	To     time.Time
+	Sort   AssignmentSort
+	Limit  int
+	Offset int
+	// MinCount leaves users with fewer assignments out of Assignments.
+	MinCount int
+}
+
+func (s *Service) Stats(ctx context.Context, q StatsQuery) (Stats, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.stats(ctx, "", q)
+}
+
+// StatsForTeam counts only PRs authored by the team's members, and review
+// assignments of those members (on any PR).
+func (s *Service) StatsForTeam(ctx context.Context, teamName string, q StatsQuery) (Stats, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists string
+	err := s.db.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", teamName).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Stats{}, newAppError(404, CodeNotFound, "team not found")
+	}
+	if err != nil {
+		return Stats{}, err
+	}
+	return s.stats(ctx, teamName, q)
+}
+
+func (s *Service) stats(ctx context.Context, teamName string, q StatsQuery) (Stats, error) {
 	var st Stats
+	fromArg, toArg := nullTime(q.From), nullTime(q.To)
 	err := s.db.QueryRowContext(ctx,
 		`SELECT
 			COUNT(*) AS total,
-			COALESCE(SUM(CASE WHEN status = 'OPEN' THEN 1 ELSE 0 END), 0) AS open,
-			COALESCE(SUM(CASE WHEN status = 'MERGED' THEN 1 ELSE 0 END), 0) AS merged
-		 FROM pull_requests`,
-	).Scan(&st.TotalPRs, &st.OpenPRs, &st.MergedPRs)
+			COALESCE(SUM(CASE WHEN pr.status = 'OPEN' THEN 1 ELSE 0 END), 0) AS open,
+			COALESCE(SUM(CASE WHEN pr.status = 'MERGED' THEN 1 ELSE 0 END), 0) AS merged,
+			COALESCE(SUM(CASE WHEN pr.status = 'CLOSED' THEN 1 ELSE 0 END), 0) AS closed,
+			COALESCE(AVG(EXTRACT(EPOCH FROM (pr.merged_at - pr.created_at))) FILTER (WHERE pr.status = 'MERGED'), 0) AS avg_merge
+		 FROM pull_requests pr
+		 JOIN users a ON a.user_id = pr.author_id
+		 WHERE ($1::timestamptz IS NULL OR pr.created_at >= $1)
+		   AND ($2::timestamptz IS NULL OR pr.created_at <= $2)
+		   AND ($3 = '' OR a.team_name = $3)`,
+		fromArg, toArg, teamName,
+	).Scan(&st.TotalPRs, &st.OpenPRs, &st.MergedPRs, &st.ClosedPRs, &st.AvgMergeSeconds)
+	if err != nil {
+		return Stats{}, err
+	}
+	st.AvgOpenAssignmentSeconds, err = s.averageAssignmentAgeOpen(ctx, time.Now(), fromArg, toArg, teamName)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	st.Assignments, err = s.assignmentStats(ctx, teamName, q)
 	if err != nil {
 		return Stats{}, err
 	}
+	all := st.Assignments
+	if q.Limit > 0 || q.Offset > 0 || q.MinCount > 0 {
+		all, err = s.assignmentStats(ctx, teamName, StatsQuery{From: q.From, To: q.To})
+		if err != nil {
+			return Stats{}, err
+		}
+	}
+	st.DistributionStdDev, st.DistributionGini = assignmentSpread(all)
+	return st, nil
+}
 
+func (s *Service) assignmentStats(ctx context.Context, teamName string, q StatsQuery) ([]AssignmentStat, error) {
+	order, ok := assignmentOrder[q.Sort]
+	if !ok {
+		order = assignmentOrder[SortCountDesc]
+	}
+	var limit sql.NullInt64
+	if q.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(q.Limit), Valid: true}
+	}
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT u.user_id, u.username, COUNT(r.pull_request_id) AS cnt
+		`SELECT u.user_id, u.username, COUNT(p.pull_request_id) AS cnt
 		 FROM users u
 		 LEFT JOIN pr_reviewers r ON u.user_id = r.user_id
+		 LEFT JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		       AND ($1::timestamptz IS NULL OR p.created_at >= $1)
+		       AND ($2::timestamptz IS NULL OR p.created_at <= $2)
+		 WHERE ($3 = '' OR u.team_name = $3)
 		 GROUP BY u.user_id, u.username
-		 ORDER BY cnt DESC, u.user_id`,
+		 HAVING COUNT(p.pull_request_id) >= $6
+		 ORDER BY `+order+`
+		 LIMIT $4 OFFSET $5`,
+		nullTime(q.From), nullTime(q.To), teamName, limit, q.Offset, q.MinCount,
 	)
 	if err != nil {
-		return Stats{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	var result []AssignmentStat
 	for rows.Next() {
 		var a AssignmentStat
 		if err := rows.Scan(&a.UserID, &a.Username, &a.Count); err != nil {
-			return Stats{}, err
+			return nil, err
 		}
-		st.Assignments = append(st.Assignments, a)
+		result = append(result, a)
 	}
 	if rows.Err() != nil {
-		return Stats{}, rows.Err()
+		return nil, rows.Err()
 	}
-	return st, nil
+	return result, nil
+}
+
+// assignmentSpread returns the population standard deviation and the Gini
+// coefficient of per-user assignment counts; both are 0 for an even load.
+func assignmentSpread(assignments []AssignmentStat) (stddev, gini float64) {
+	n := len(assignments)
+	if n == 0 {
+		return 0, 0
+	}
+	counts := make([]float64, n)
+	var total float64
+	for i, a := range assignments {
+		counts[i] = float64(a.Count)
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	mean := total / float64(n)
+	var variance, weighted float64
+	slices.Sort(counts)
+	for i, c := range counts {
+		variance += (c - mean) * (c - mean)
+		weighted += float64(i+1) * c
+	}
+	stddev = math.Sqrt(variance / float64(n))
+	gini = 2*weighted/(float64(n)*total) - float64(n+1)/float64(n)
+	return stddev, gini
+}
+
+// AverageAssignmentAgeOpen returns the mean time in seconds that reviewers of
+// open PRs have been assigned as of now; 0 when there are none.
+func (s *Service) AverageAssignmentAgeOpen(ctx context.Context, now time.Time) (float64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.averageAssignmentAgeOpen(ctx, now, sql.NullTime{}, sql.NullTime{}, "")
+}
+
+// averageAssignmentAgeOpen applies the same created_at window as the PR
+// totals of stats, so both describe the same set of PRs.
+func (s *Service) averageAssignmentAgeOpen(ctx context.Context, now time.Time, from, to sql.NullTime, teamName string) (float64, error) {
+	var avg float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(AVG(EXTRACT(EPOCH FROM ($1::timestamptz - r.assigned_at))), 0)
+		 FROM pr_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 JOIN users u ON u.user_id = r.user_id
+		 WHERE p.status = 'OPEN'
+		   AND ($2::timestamptz IS NULL OR p.created_at >= $2)
+		   AND ($3::timestamptz IS NULL OR p.created_at <= $3)
+		   AND ($4 = '' OR u.team_name = $4)`,
+		now, from, to, teamName,
+	).Scan(&avg)
+	return avg, err
 }
 
-func pickRandom(rnd *rand.Rand, ids []string, limit int) []string {
+func (s *Service) pickRandom(ids []string, limit int) []string {
 	if len(ids) == 0 || limit <= 0 {
-		return nil
+		return []string{}
 	}
-	rnd.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	s.rndMu.Lock()
+	s.rnd.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	s.rndMu.Unlock()
 	if len(ids) > limit {
 		return append([]string{}, ids[:limit]...)
 	}
 	return append([]string{}, ids...)
 }
 
+// IsTimeout reports whether err means the operation ran out of time: either
+// its context expired, or Postgres canceled the statement (query_canceled),
+// which is what lib/pq returns when the context expires mid-query.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// utcPtr normalizes timestamps returned to clients to UTC regardless of the
+// database session time zone.
+func utcPtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	u := t.Time.UTC()
+	return &u
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+// pickRoundRobin takes the next ids (sorted by user_id) after the team cursor,
+// wrapping around, and moves the cursor to the last picked id.
+func (s *Service) pickRoundRobin(ctx context.Context, tx *sql.Tx, teamName string, ids []string, limit int) ([]string, error) {
+	if len(ids) == 0 || limit <= 0 {
+		return []string{}, nil
+	}
+
+	var last string
+	err := tx.QueryRowContext(ctx,
+		`SELECT last_user_id FROM team_assignment_cursor WHERE team_name = $1 FOR UPDATE`,
+		teamName,
+	).Scan(&last)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	start := 0
+	for start < len(ids) && ids[start] <= last {
+		start++
+	}
+	n := min(limit, len(ids))
+	picked := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		picked = append(picked, ids[(start+i)%len(ids)])
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO team_assignment_cursor (team_name, last_user_id) VALUES ($1, $2)
+		 ON CONFLICT (team_name) DO UPDATE SET last_user_id = EXCLUDED.last_user_id`,
+		teamName, picked[len(picked)-1],
+	); err != nil {
+		return nil, fmt.Errorf("advance assignment cursor: %w", err)
+	}
+	return picked, nil
+}
+
 func contains(list []string, target string) bool {
 	for _, v := range list {
 		if v == target {
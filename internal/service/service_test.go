@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewWithRand(dbtest.Open(t), rand.New(rand.NewSource(1)))
+}
+
+func activeMember(id string) models.TeamMember {
+	return models.TeamMember{UserID: id, Username: "user-" + id, IsActive: true}
+}
+
+func inactiveMember(id string) models.TeamMember {
+	m := activeMember(id)
+	m.IsActive = false
+	return m
+}
+
+func mustCreateTeam(t *testing.T, s *Service, name string, members ...models.TeamMember) models.Team {
+	t.Helper()
+	team, err := s.CreateTeam(context.Background(), models.Team{TeamName: name, Members: members})
+	if err != nil {
+		t.Fatalf("create team %s: %v", name, err)
+	}
+	return team
+}
+
+func mustCreatePR(t *testing.T, s *Service, input CreatePRInput) models.PullRequest {
+	t.Helper()
+	if input.Name == "" {
+		input.Name = "PR " + input.ID
+	}
+	pr, err := s.CreatePullRequest(context.Background(), input)
+	if err != nil {
+		t.Fatalf("create PR %s: %v", input.ID, err)
+	}
+	return pr
+}
+
+func mustExec(t *testing.T, s *Service, query string, args ...any) {
+	t.Helper()
+	if _, err := s.db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("got error %v, want %s", err, code)
+	}
+	if appErr.Code != code {
+		t.Fatalf("got code %s (%s), want %s", appErr.Code, appErr.Message, code)
+	}
+}
+
+func assertReviewers(t *testing.T, pr models.PullRequest, want ...string) {
+	t.Helper()
+	got := slices.Clone(pr.AssignedReviewers)
+	slices.Sort(got)
+	want = slices.Clone(want)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("reviewers of %s = %v, want %v", pr.ID, got, want)
+	}
+}
+
+func TestCreatePullRequestReviewerCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []string
+		count   int
+		want    int
+	}{
+		{name: "one", members: []string{"u2", "u3", "u4"}, count: 1, want: 1},
+		{name: "three of two candidates", members: []string{"u2", "u3"}, count: 3, want: 2},
+		{name: "default", members: []string{"u2", "u3", "u4"}, count: 0, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			members := []models.TeamMember{activeMember("u1")}
+			for _, id := range tt.members {
+				members = append(members, activeMember(id))
+			}
+			mustCreateTeam(t, s, "backend", members...)
+
+			pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: tt.count})
+			if len(pr.AssignedReviewers) != tt.want {
+				t.Fatalf("got %d reviewers %v, want %d", len(pr.AssignedReviewers), pr.AssignedReviewers, tt.want)
+			}
+			for _, id := range pr.AssignedReviewers {
+				if !slices.Contains(tt.members, id) {
+					t.Fatalf("unexpected reviewer %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatePullRequestNeverAssignsAuthor(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "solo", activeMember("u1"), inactiveMember("u2"))
+	mustCreateTeam(t, s, "backend", activeMember("b1"), activeMember("b2"), activeMember("b3"))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-solo", Author: "u1"})
+	if pr.AssignedReviewers == nil || len(pr.AssignedReviewers) != 0 {
+		t.Fatalf("reviewers = %#v, want an empty list", pr.AssignedReviewers)
+	}
+
+	for _, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4"} {
+		pr := mustCreatePR(t, s, CreatePRInput{ID: id, Author: "b1"})
+		if slices.Contains(pr.AssignedReviewers, "b1") {
+			t.Fatalf("%s: author assigned as reviewer: %v", id, pr.AssignedReviewers)
+		}
+	}
+}
+
+func TestCreatePullRequestRequireReviewer(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "solo", activeMember("u1"), inactiveMember("u2"))
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	if len(pr.AssignedReviewers) != 0 {
+		t.Fatalf("reviewers = %v, want none", pr.AssignedReviewers)
+	}
+
+	s.SetRequireReviewer(true)
+	_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-2", Name: "PR 2", Author: "u1"})
+	assertCode(t, err, CodeNoCandidate)
+	_, err = s.GetPullRequest(ctx, "pr-2")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestCreatePullRequestMinReviewers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input CreatePRInput
+		code  string
+		want  int
+	}{
+		{name: "unset", input: CreatePRInput{ReviewerCount: 3}, want: 2},
+		{name: "sufficient", input: CreatePRInput{MinReviewers: 2}, want: 2},
+		{name: "insufficient", input: CreatePRInput{ReviewerCount: 3, MinReviewers: 3}, code: CodeInsufficientReviewers},
+		{name: "above reviewer_count", input: CreatePRInput{ReviewerCount: 1, MinReviewers: 2}, code: CodeBadRequest},
+		{name: "above team default", input: CreatePRInput{MinReviewers: 3}, code: CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestService(t)
+			mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+
+			input := tt.input
+			input.ID, input.Name, input.Author = "pr-1", "PR 1", "u1"
+			pr, err := s.CreatePullRequest(ctx, input)
+			if tt.code != "" {
+				assertCode(t, err, tt.code)
+				_, err = s.GetPullRequest(ctx, "pr-1")
+				assertCode(t, err, CodeNotFound)
+				return
+			}
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if len(pr.AssignedReviewers) != tt.want {
+				t.Fatalf("reviewers = %v, want %d", pr.AssignedReviewers, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePullRequestCrossTeam(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"))
+	mustCreateTeam(t, s, "mobile", activeMember("m1"), activeMember("m2"), activeMember("m3"))
+	if err := s.LinkTeams(ctx, "backend", "frontend"); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if err := s.LinkTeams(ctx, "mobile", "frontend"); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	assertReviewers(t, pr, "u2")
+
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u1", CrossTeam: true})
+	assertReviewers(t, pr, "u2", "f1")
+
+	// mobile has enough own reviewers, so the linked team is not used
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "m1", CrossTeam: true})
+	for _, id := range pr.AssignedReviewers {
+		if id == "f1" {
+			t.Fatalf("linked team reviewer picked although home team had enough: %v", pr.AssignedReviewers)
+		}
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers = %v, want 2", pr.AssignedReviewers)
+	}
+}
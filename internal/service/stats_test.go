@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// setPRTimes overwrites the PR timestamps so stats can be checked against
+// known values; a zero mergedAt leaves merged_at untouched.
+func setPRTimes(t *testing.T, s *Service, prID string, createdAt, mergedAt time.Time) {
+	t.Helper()
+	mustExec(t, s, `UPDATE pull_requests SET created_at = $2 WHERE pull_request_id = $1`, prID, createdAt)
+	if !mergedAt.IsZero() {
+		mustExec(t, s, `UPDATE pull_requests SET merged_at = $2 WHERE pull_request_id = $1`, prID, mergedAt)
+	}
+}
+
+func TestStatsAverageMergeTime(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "u1"})
+
+	st, err := s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if st.AvgMergeSeconds != 0 {
+		t.Fatalf("avg merge without merged PRs = %v, want 0", st.AvgMergeSeconds)
+	}
+
+	for _, id := range []string{"pr-1", "pr-2"} {
+		if _, err := s.MergePullRequest(ctx, id); err != nil {
+			t.Fatalf("merge %s: %v", id, err)
+		}
+	}
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	setPRTimes(t, s, "pr-1", base, base.Add(time.Hour))
+	setPRTimes(t, s, "pr-2", base, base.Add(3*time.Hour))
+
+	st, err = s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if !approxEqual(st.AvgMergeSeconds, 2*3600) {
+		t.Fatalf("avg merge = %v, want 7200", st.AvgMergeSeconds)
+	}
+	if st.TotalPRs != 3 || st.MergedPRs != 2 || st.OpenPRs != 1 {
+		t.Fatalf("counts = %+v", st)
+	}
+}
+
+func assignmentCount(st Stats, userID string) int {
+	for _, a := range st.Assignments {
+		if a.UserID == userID {
+			return a.Count
+		}
+	}
+	return -1
+}
+
+func TestStatsTimeRange(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	jan := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	for id, created := range map[string]time.Time{"jan-1": jan, "jan-2": jan.Add(24 * time.Hour), "feb-1": feb} {
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+		setPRTimes(t, s, id, created, time.Time{})
+	}
+
+	tests := []struct {
+		name     string
+		q        StatsQuery
+		total    int
+		reviewed int
+	}{
+		{name: "all", q: StatsQuery{}, total: 3, reviewed: 3},
+		{name: "january", q: StatsQuery{From: jan.AddDate(0, 0, -9), To: jan.AddDate(0, 0, 21)}, total: 2, reviewed: 2},
+		{name: "february", q: StatsQuery{From: feb.AddDate(0, 0, -9)}, total: 1, reviewed: 1},
+		{name: "until january", q: StatsQuery{To: jan.Add(time.Hour)}, total: 1, reviewed: 1},
+		{name: "empty", q: StatsQuery{From: feb.AddDate(0, 1, 0)}, total: 0, reviewed: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := s.Stats(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("stats: %v", err)
+			}
+			if st.TotalPRs != tt.total {
+				t.Fatalf("total = %d, want %d", st.TotalPRs, tt.total)
+			}
+			if got := assignmentCount(st, "u2"); got != tt.reviewed {
+				t.Fatalf("u2 assignments = %d, want %d", got, tt.reviewed)
+			}
+		})
+	}
+}
+
+func TestAverageAssignmentAgeOpen(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	avg, err := s.AverageAssignmentAgeOpen(ctx, now)
+	if err != nil {
+		t.Fatalf("average age: %v", err)
+	}
+	if avg != 0 {
+		t.Fatalf("average age without assignments = %v, want 0", avg)
+	}
+
+	open := mustCreatePR(t, s, CreatePRInput{ID: "open", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "merged", Author: "u1"})
+	if _, err := s.MergePullRequest(ctx, "merged"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $1`, now.Add(-10*time.Hour))
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $3 WHERE pull_request_id = $1 AND user_id = $2`,
+		open.ID, open.AssignedReviewers[0], now.Add(-time.Hour))
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $3 WHERE pull_request_id = $1 AND user_id = $2`,
+		open.ID, open.AssignedReviewers[1], now.Add(-3*time.Hour))
+
+	// merged PRs are ignored even though their assignments are older
+	avg, err = s.AverageAssignmentAgeOpen(ctx, now)
+	if err != nil {
+		t.Fatalf("average age: %v", err)
+	}
+	if !approxEqual(avg, 2*3600) {
+		t.Fatalf("average age = %v, want 7200", avg)
+	}
+}
+
+func TestReassignedReviewerGetsFreshTimestamp(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1", ReviewerCount: 1})
+	old := time.Now().Add(-24 * time.Hour)
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $1`, old)
+
+	replacedBy := mustReassign(t, s, pr.ID, pr.AssignedReviewers[0])
+	got, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	if len(got.ReviewersDetailed) != 1 || got.ReviewersDetailed[0].UserID != replacedBy {
+		t.Fatalf("reviewers = %+v", got.ReviewersDetailed)
+	}
+	if !got.ReviewersDetailed[0].AssignedAt.After(old.Add(time.Hour)) {
+		t.Fatalf("replacement assigned_at = %s, want a fresh timestamp", got.ReviewersDetailed[0].AssignedAt)
+	}
+}
+
+func TestAssignmentSpread(t *testing.T) {
+	counts := func(ns ...int) []AssignmentStat {
+		out := make([]AssignmentStat, len(ns))
+		for i, n := range ns {
+			out[i] = AssignmentStat{UserID: fmt.Sprintf("u%d", i), Count: n}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		counts []AssignmentStat
+		stddev float64
+		gini   float64
+	}{
+		{name: "none"},
+		{name: "all zero", counts: counts(0, 0, 0)},
+		{name: "even", counts: counts(2, 2, 2)},
+		{name: "skewed", counts: counts(0, 6, 0), stddev: math.Sqrt(8), gini: 2.0 / 3},
+		{name: "mild", counts: counts(1, 3), stddev: 1, gini: 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stddev, gini := assignmentSpread(tt.counts)
+			if !approxEqual(stddev, tt.stddev) || !approxEqual(gini, tt.gini) {
+				t.Fatalf("spread = (%v, %v), want (%v, %v)", stddev, gini, tt.stddev, tt.gini)
+			}
+		})
+	}
+}
+
+func TestStatsDistribution(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	// each member authors once and reviews the other two: an even load
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u2"})
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "u3"})
+
+	st, err := s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if st.DistributionStdDev != 0 || st.DistributionGini != 0 {
+		t.Fatalf("even load spread = (%v, %v), want 0", st.DistributionStdDev, st.DistributionGini)
+	}
+
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-4", Author: "u2", ReviewerCount: 1})
+	st, err = s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if st.DistributionStdDev <= 0 || st.DistributionGini <= 0 {
+		t.Fatalf("skewed load spread = (%v, %v), want > 0", st.DistributionStdDev, st.DistributionGini)
+	}
+}
+
+func TestStatsForTeam(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+	mustCreatePR(t, s, CreatePRInput{ID: "be-1", Author: "u1"})
+	mustCreatePR(t, s, CreatePRInput{ID: "be-2", Author: "u2"})
+	if _, err := s.MergePullRequest(ctx, "be-2"); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	mustCreatePR(t, s, CreatePRInput{ID: "fe-1", Author: "f1"})
+
+	global, err := s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	backend, err := s.StatsForTeam(ctx, "backend", StatsQuery{})
+	if err != nil {
+		t.Fatalf("backend stats: %v", err)
+	}
+	frontend, err := s.StatsForTeam(ctx, "frontend", StatsQuery{})
+	if err != nil {
+		t.Fatalf("frontend stats: %v", err)
+	}
+
+	if global.TotalPRs != 3 || backend.TotalPRs != 2 || frontend.TotalPRs != 1 {
+		t.Fatalf("total PRs = global %d, backend %d, frontend %d", global.TotalPRs, backend.TotalPRs, frontend.TotalPRs)
+	}
+	if backend.OpenPRs != 1 || backend.MergedPRs != 1 || frontend.OpenPRs != 1 || frontend.MergedPRs != 0 {
+		t.Fatalf("status counts = backend %+v, frontend %+v", backend, frontend)
+	}
+	if len(global.Assignments) != 5 || len(backend.Assignments) != 3 || len(frontend.Assignments) != 2 {
+		t.Fatalf("assignment rows = global %d, backend %d, frontend %d",
+			len(global.Assignments), len(backend.Assignments), len(frontend.Assignments))
+	}
+	for _, a := range backend.Assignments {
+		if got := assignmentCount(global, a.UserID); got != a.Count {
+			t.Fatalf("%s: team count %d, global count %d", a.UserID, a.Count, got)
+		}
+	}
+	if assignmentCount(frontend, "f2") != 1 || assignmentCount(frontend, "u1") != -1 {
+		t.Fatalf("frontend assignments = %+v", frontend.Assignments)
+	}
+
+	_, err = s.StatsForTeam(ctx, "missing", StatsQuery{})
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestStatsAssignmentSortAndPagination(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", named("u1", "dave"), named("u2", "carol"), named("u3", "bob"), named("u4", "alice"))
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+	}
+	// u2 and u4 tie on 3, u3 has 1, u1 none
+	mustExec(t, s, `DELETE FROM pr_reviewers`)
+	mustExec(t, s, `INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES
+		('pr-1', 'u2'), ('pr-1', 'u4'), ('pr-1', 'u3'),
+		('pr-2', 'u2'), ('pr-2', 'u4'),
+		('pr-3', 'u2'), ('pr-3', 'u4')`)
+
+	tests := []struct {
+		name string
+		q    StatsQuery
+		want []string
+	}{
+		{name: "default", q: StatsQuery{}, want: []string{"u2", "u4", "u3", "u1"}},
+		{name: "count desc", q: StatsQuery{Sort: SortCountDesc}, want: []string{"u2", "u4", "u3", "u1"}},
+		{name: "count asc", q: StatsQuery{Sort: SortCountAsc}, want: []string{"u1", "u3", "u2", "u4"}},
+		{name: "username", q: StatsQuery{Sort: SortUsername}, want: []string{"u4", "u3", "u2", "u1"}},
+		{name: "first page", q: StatsQuery{Limit: 2}, want: []string{"u2", "u4"}},
+		{name: "middle page", q: StatsQuery{Sort: SortUsername, Limit: 2, Offset: 1}, want: []string{"u3", "u2"}},
+		{name: "last partial page", q: StatsQuery{Limit: 3, Offset: 3}, want: []string{"u1"}},
+		{name: "past the end", q: StatsQuery{Limit: 2, Offset: 4}, want: nil},
+		{name: "limit above total", q: StatsQuery{Limit: 100}, want: []string{"u2", "u4", "u3", "u1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := s.Stats(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("stats: %v", err)
+			}
+			var got []string
+			for _, a := range st.Assignments {
+				got = append(got, a.UserID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("assignments = %v, want %v", got, tt.want)
+			}
+			// paging does not change the totals
+			if st.TotalPRs != 3 {
+				t.Fatalf("total PRs = %d, want 3", st.TotalPRs)
+			}
+		})
+	}
+}
+
+func TestStatsAssignmentFilters(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+	for _, id := range []string{"pr-1", "pr-2", "pr-3"} {
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1"})
+	}
+	// u2: 3, f2: 2, u3: 1, u1 and f1: 0
+	mustExec(t, s, `DELETE FROM pr_reviewers`)
+	mustExec(t, s, `INSERT INTO pr_reviewers (pull_request_id, user_id) VALUES
+		('pr-1', 'u2'), ('pr-2', 'u2'), ('pr-3', 'u2'),
+		('pr-1', 'f2'), ('pr-2', 'f2'),
+		('pr-1', 'u3')`)
+
+	ids := func(st Stats) []string {
+		var out []string
+		for _, a := range st.Assignments {
+			out = append(out, a.UserID)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		team string
+		q    StatsQuery
+		want []string
+	}{
+		{name: "no params", q: StatsQuery{}, want: []string{"u2", "f2", "u3", "f1", "u1"}},
+		{name: "min_count 1 drops idle users", q: StatsQuery{MinCount: 1}, want: []string{"u2", "f2", "u3"}},
+		{name: "min_count 3", q: StatsQuery{MinCount: 3}, want: []string{"u2"}},
+		{name: "min_count above everyone", q: StatsQuery{MinCount: 4}, want: nil},
+		{name: "limit", q: StatsQuery{Limit: 2}, want: []string{"u2", "f2"}},
+		{name: "min_count with limit", q: StatsQuery{MinCount: 1, Limit: 2, Offset: 1}, want: []string{"f2", "u3"}},
+		{name: "team", team: "frontend", q: StatsQuery{}, want: []string{"f2", "f1"}},
+		{name: "team with min_count", team: "backend", q: StatsQuery{MinCount: 1}, want: []string{"u2", "u3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var st Stats
+			var err error
+			if tt.team == "" {
+				st, err = s.Stats(ctx, tt.q)
+			} else {
+				st, err = s.StatsForTeam(ctx, tt.team, tt.q)
+			}
+			if err != nil {
+				t.Fatalf("stats: %v", err)
+			}
+			if got := ids(st); !slices.Equal(got, tt.want) {
+				t.Fatalf("assignments = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the spread always describes everyone in scope, not the filtered page
+	all, err := s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	filtered, err := s.Stats(ctx, StatsQuery{MinCount: 2, Limit: 1})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if filtered.DistributionStdDev != all.DistributionStdDev || filtered.DistributionGini != all.DistributionGini {
+		t.Fatalf("filtered spread (%v, %v) differs from (%v, %v)",
+			filtered.DistributionStdDev, filtered.DistributionGini, all.DistributionStdDev, all.DistributionGini)
+	}
+}
+
+func TestStatsOpenAssignmentAgeHonoursTimeRange(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	jan := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC)
+	mustCreatePR(t, s, CreatePRInput{ID: "jan", Author: "u1", ReviewerCount: 1})
+	mustCreatePR(t, s, CreatePRInput{ID: "feb", Author: "u1", ReviewerCount: 1})
+	setPRTimes(t, s, "jan", jan, time.Time{})
+	setPRTimes(t, s, "feb", feb, time.Time{})
+	// the January assignment is far older, so including it would be obvious
+	now := time.Now()
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $2 WHERE pull_request_id = $1`, "jan", now.Add(-1000*time.Hour))
+	mustExec(t, s, `UPDATE pr_reviewers SET assigned_at = $2 WHERE pull_request_id = $1`, "feb", now.Add(-time.Hour))
+
+	st, err := s.Stats(ctx, StatsQuery{From: feb.AddDate(0, 0, -1)})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if st.OpenPRs != 1 {
+		t.Fatalf("open = %d, want 1", st.OpenPRs)
+	}
+	// stats measures against time.Now, so allow for the test's own runtime
+	if math.Abs(st.AvgOpenAssignmentSeconds-3600) > 60 {
+		t.Fatalf("average open assignment age = %v, want about 3600 without the January PR", st.AvgOpenAssignmentSeconds)
+	}
+
+	st, err = s.Stats(ctx, StatsQuery{})
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if math.Abs(st.AvgOpenAssignmentSeconds-500.5*3600) > 60 {
+		t.Fatalf("average open assignment age = %v, want the mean of both PRs", st.AvgOpenAssignmentSeconds)
+	}
+}
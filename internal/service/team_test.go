@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestRenameTeamMovesMembersAndPRs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+
+	renamed, err := s.RenameTeam(ctx, "backend", "platform")
+	if err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if renamed.TeamName != "platform" || len(renamed.Members) != 3 {
+		t.Fatalf("renamed team = %+v", renamed)
+	}
+	_, err = s.GetTeam(ctx, "backend", GetTeamOptions{})
+	assertCode(t, err, CodeNotFound)
+
+	var teamName string
+	if err := s.db.QueryRow(`SELECT team_name FROM users WHERE user_id = 'u2'`).Scan(&teamName); err != nil {
+		t.Fatalf("load member: %v", err)
+	}
+	if teamName != "platform" {
+		t.Fatalf("member team = %s, want platform", teamName)
+	}
+
+	got, err := s.GetPullRequest(ctx, pr.ID)
+	if err != nil {
+		t.Fatalf("get PR after rename: %v", err)
+	}
+	if got.AuthorID != "u1" || len(got.AssignedReviewers) != 2 {
+		t.Fatalf("PR after rename = %+v", got)
+	}
+	// the renamed team keeps assigning reviewers to its members' PRs
+	next := mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "u2"})
+	assertReviewers(t, next, "u1", "u3")
+}
+
+func TestAddTeamMembers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+
+	renamed := activeMember("u2")
+	renamed.Username = "Bobby"
+	team, err := s.AddTeamMembers(ctx, "backend", []models.TeamMember{renamed, activeMember("u3"), inactiveMember("u4")})
+	if err != nil {
+		t.Fatalf("add members: %v", err)
+	}
+	if len(team.Members) != 4 {
+		t.Fatalf("members = %+v, want 4", team.Members)
+	}
+	if team.Members[1].UserID != "u2" || team.Members[1].Username != "Bobby" {
+		t.Fatalf("existing member not updated: %+v", team.Members[1])
+	}
+	if team.ActiveCount != 3 {
+		t.Fatalf("active count = %d, want 3", team.ActiveCount)
+	}
+
+	_, err = s.AddTeamMembers(ctx, "missing", []models.TeamMember{activeMember("u5")})
+	assertCode(t, err, CodeNotFound)
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE user_id = 'u5'`).Scan(&n); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("member of a missing team was created")
+	}
+}
+
+func named(id, username string) models.TeamMember {
+	m := activeMember(id)
+	m.Username = username
+	return m
+}
+
+func TestUsernamesUniqueWithinTeam(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+
+	_, err := s.CreateTeam(ctx, models.Team{TeamName: "frontend", Members: []models.TeamMember{
+		activeMember("u4"), named("u5", "alice"), named("u6", "alice"),
+	}})
+	assertCode(t, err, CodeUsernameExists)
+
+	_, err = s.AddTeamMembers(ctx, "backend", []models.TeamMember{named("u3", "user-u1")})
+	assertCode(t, err, CodeUsernameExists)
+
+	// the same username in another team is fine
+	mustCreateTeam(t, s, "frontend", activeMember("u4"), named("u3", "user-u1"))
+}
+
+func TestDeleteTeam(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "u1"})
+
+	_, err := s.DeleteTeam(ctx, "backend")
+	assertCode(t, err, CodeTeamInUse)
+	if _, err := s.GetTeam(ctx, "backend", GetTeamOptions{}); err != nil {
+		t.Fatalf("blocked delete removed the team: %v", err)
+	}
+
+	// merged PRs still block: only CLOSED ones may go with the team
+	if _, err := s.MergePullRequest(ctx, pr.ID); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	_, err = s.DeleteTeam(ctx, "backend")
+	assertCode(t, err, CodeTeamInUse)
+
+	mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "f1"})
+	if _, err := s.ClosePullRequest(ctx, "pr-2"); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	deleted, err := s.DeleteTeam(ctx, "frontend")
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("deleted %d members, want 2", deleted)
+	}
+	_, err = s.GetTeam(ctx, "frontend", GetTeamOptions{})
+	assertCode(t, err, CodeNotFound)
+	_, err = s.GetPullRequest(ctx, "pr-2")
+	assertCode(t, err, CodeNotFound)
+
+	_, err = s.DeleteTeam(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestRenameTeamErrors(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"))
+
+	_, err := s.RenameTeam(ctx, "backend", "frontend")
+	assertCode(t, err, CodeTeamExists)
+	_, err = s.RenameTeam(ctx, "missing", "platform")
+	assertCode(t, err, CodeNotFound)
+
+	team, err := s.GetTeam(ctx, "backend", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("failed renames changed the team: %v", err)
+	}
+	if len(team.Members) != 1 || team.Members[0].UserID != "u1" {
+		t.Fatalf("team after failed rename = %+v", team)
+	}
+}
+
+func TestGetTeamCountsActiveMembers(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), inactiveMember("u2"), activeMember("u3"), inactiveMember("u4"), activeMember("u5"))
+	mustCreateTeam(t, s, "idle", inactiveMember("i1"))
+
+	team, err := s.GetTeam(ctx, "backend", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if team.ActiveCount != 3 || team.TotalCount != 5 {
+		t.Fatalf("counts = active %d, total %d, want 3 and 5", team.ActiveCount, team.TotalCount)
+	}
+
+	// deactivating a member shows up in the next read
+	if _, err := s.SetUserActive(ctx, "u1", false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	team, err = s.GetTeam(ctx, "backend", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if team.ActiveCount != 2 || team.TotalCount != 5 {
+		t.Fatalf("counts = active %d, total %d, want 2 and 5", team.ActiveCount, team.TotalCount)
+	}
+
+	idle, err := s.GetTeam(ctx, "idle", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if idle.ActiveCount != 0 || idle.TotalCount != 1 {
+		t.Fatalf("counts = active %d, total %d, want 0 and 1", idle.ActiveCount, idle.TotalCount)
+	}
+}
+
+func TestArchivedTeamMembersAreNotPicked(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"), activeMember("f2"))
+	if err := s.LinkTeams(ctx, "backend", "frontend"); err != nil {
+		t.Fatalf("link teams: %v", err)
+	}
+	before := mustCreatePR(t, s, CreatePRInput{ID: "pr-before", Author: "u1", ReviewerCount: 1})
+
+	archived, err := s.ArchiveTeam(ctx, "backend")
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if !archived.IsArchived {
+		t.Fatalf("archived team = %+v", archived)
+	}
+	if _, err := s.ArchiveTeam(ctx, "backend"); err != nil {
+		t.Fatalf("archive twice: %v", err)
+	}
+
+	// the author's own archived team yields nobody, not even the author
+	pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-own", Author: "u1", AllowSelfReview: true})
+	assertReviewers(t, pr)
+
+	// a linked team may not borrow members of an archived team either
+	pr = mustCreatePR(t, s, CreatePRInput{ID: "pr-linked", Author: "f1", ReviewerCount: 2, CrossTeam: true})
+	assertReviewers(t, pr, "f2")
+
+	_, _, err = s.ReassignReviewer(ctx, before.ID, before.AssignedReviewers[0])
+	assertCode(t, err, CodeNoCandidate)
+
+	_, err = s.GetTeam(ctx, "backend", GetTeamOptions{})
+	assertCode(t, err, CodeNotFound)
+	if _, err := s.GetTeam(ctx, "backend", GetTeamOptions{IncludeArchived: true}); err != nil {
+		t.Fatalf("get archived team: %v", err)
+	}
+	_, err = s.ArchiveTeam(ctx, "missing")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestGetTeamActiveOnlyCounts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), inactiveMember("u2"), activeMember("u3"), inactiveMember("u4"))
+
+	tests := []struct {
+		name    string
+		opts    GetTeamOptions
+		members []string
+	}{
+		{name: "all members", opts: GetTeamOptions{}, members: []string{"u1", "u2", "u3", "u4"}},
+		{name: "active only", opts: GetTeamOptions{ActiveOnly: true}, members: []string{"u1", "u3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			team, err := s.GetTeam(ctx, "backend", tt.opts)
+			if err != nil {
+				t.Fatalf("get team: %v", err)
+			}
+			var got []string
+			for _, m := range team.Members {
+				got = append(got, m.UserID)
+			}
+			if !slices.Equal(got, tt.members) {
+				t.Fatalf("members = %v, want %v", got, tt.members)
+			}
+			// member_count follows the returned list, the others the whole team
+			if team.MemberCount != len(tt.members) || team.ActiveCount != 2 || team.TotalCount != 4 {
+				t.Fatalf("counts = member %d, active %d, total %d; want %d, 2, 4",
+					team.MemberCount, team.ActiveCount, team.TotalCount, len(tt.members))
+			}
+		})
+	}
+}
+
+func TestUpdateTeamMember(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"))
+	mustCreateTeam(t, s, "frontend", activeMember("f1"))
+	username := func(v string) *string { return &v }
+	active := func(v bool) *bool { return &v }
+
+	user, err := s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "u1", Username: username("Alice")})
+	if err != nil {
+		t.Fatalf("update username: %v", err)
+	}
+	if user.Username != "Alice" || !user.IsActive {
+		t.Fatalf("after username update = %+v, want Alice and still active", user)
+	}
+
+	user, err = s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "u1", IsActive: active(false)})
+	if err != nil {
+		t.Fatalf("update is_active: %v", err)
+	}
+	if user.Username != "Alice" || user.IsActive {
+		t.Fatalf("after is_active update = %+v, want Alice and inactive", user)
+	}
+
+	_, err = s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "u2", Username: username("Alice")})
+	assertCode(t, err, CodeUsernameExists)
+
+	// neither a stranger nor a member of another team is touched or created
+	_, err = s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "u9", Username: username("new")})
+	assertCode(t, err, CodeNotFound)
+	_, err = s.UpdateTeamMember(ctx, "backend", MemberUpdate{UserID: "f1", IsActive: active(false)})
+	assertCode(t, err, CodeNotFound)
+
+	team, err := s.GetTeam(ctx, "backend", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if team.TotalCount != 2 || team.ActiveCount != 1 {
+		t.Fatalf("team after updates = %+v", team)
+	}
+	other, err := s.GetTeam(ctx, "frontend", GetTeamOptions{})
+	if err != nil {
+		t.Fatalf("get team: %v", err)
+	}
+	if !other.Members[0].IsActive {
+		t.Fatalf("member of another team was deactivated")
+	}
+}
+
+func TestTeamDefaultReviewerCount(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+	members := func(prefix string) []models.TeamMember {
+		var ms []models.TeamMember
+		for _, id := range []string{"1", "2", "3", "4", "5"} {
+			ms = append(ms, activeMember(prefix+id))
+		}
+		return ms
+	}
+	frontend, err := s.CreateTeam(ctx, models.Team{TeamName: "frontend", Members: members("f"), DefaultReviewerCount: 1})
+	if err != nil {
+		t.Fatalf("create frontend: %v", err)
+	}
+	if frontend.DefaultReviewerCount != 1 {
+		t.Fatalf("frontend default = %d, want 1", frontend.DefaultReviewerCount)
+	}
+	backend := mustCreateTeam(t, s, "backend", members("b")...)
+	if backend.DefaultReviewerCount != defaultReviewerCount {
+		t.Fatalf("backend default = %d, want %d", backend.DefaultReviewerCount, defaultReviewerCount)
+	}
+
+	if pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-1", Author: "f1"}); len(pr.AssignedReviewers) != 1 {
+		t.Fatalf("frontend PR reviewers = %v, want the team default of 1", pr.AssignedReviewers)
+	}
+	if pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-2", Author: "b1"}); len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("backend PR reviewers = %v, want 2", pr.AssignedReviewers)
+	}
+	// an explicit count wins over the team default
+	if pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-3", Author: "f1", ReviewerCount: 3}); len(pr.AssignedReviewers) != 3 {
+		t.Fatalf("explicit count reviewers = %v, want 3", pr.AssignedReviewers)
+	}
+
+	team, err := s.SetTeamReviewerCount(ctx, "backend", 3)
+	if err != nil {
+		t.Fatalf("set reviewer count: %v", err)
+	}
+	if team.DefaultReviewerCount != 3 {
+		t.Fatalf("backend default = %d, want 3", team.DefaultReviewerCount)
+	}
+	if pr := mustCreatePR(t, s, CreatePRInput{ID: "pr-4", Author: "b1"}); len(pr.AssignedReviewers) != 3 {
+		t.Fatalf("backend PR reviewers = %v, want the new default of 3", pr.AssignedReviewers)
+	}
+
+	_, err = s.SetTeamReviewerCount(ctx, "missing", 1)
+	assertCode(t, err, CodeNotFound)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// unreachableDB fails every query with a connection error, so a
+// DeadlineExceeded can only come from the context.
+func unreachableDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestOperationsHonourOpTimeout(t *testing.T) {
+	s := NewWithRand(unreachableDB(t), nil)
+	s.SetOpTimeout(time.Nanosecond)
+	ctx := context.Background()
+
+	ops := map[string]func() error{
+		"ReassignAllForUser": func() error { _, err := s.ReassignAllForUser(ctx, "u1"); return err },
+		"CreatePullRequest": func() error {
+			_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-1", Name: "PR", Author: "u1"})
+			return err
+		},
+		"ReassignReviewer": func() error { _, _, err := s.ReassignReviewer(ctx, "pr-1", "u2"); return err },
+		"SetUserActive":    func() error { _, err := s.SetUserActive(ctx, "u1", false); return err },
+	}
+	for name, op := range ops {
+		if err := op(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: err = %v, want DeadlineExceeded", name, err)
+		}
+	}
+}
+
+func TestNearDeadlineContextFailsCleanly(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Microsecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.CreatePullRequest(ctx, CreatePRInput{ID: "pr-1", Name: "PR", Author: "u1"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("create with an expired context hung")
+	}
+
+	// nothing from the aborted operation is left behind
+	_, err := s.GetPullRequest(context.Background(), "pr-1")
+	assertCode(t, err, CodeNotFound)
+}
+
+func TestReassignAllForUserBoundsEachPR(t *testing.T) {
+	s := newTestService(t)
+	mustCreateTeam(t, s, "backend", activeMember("u1"), activeMember("u2"), activeMember("u3"), activeMember("u4"))
+	for _, id := range []string{"pr-1", "pr-2", "pr-3", "pr-4"} {
+		mustCreatePR(t, s, CreatePRInput{ID: id, Author: "u1", ReviewerCount: 1})
+	}
+	mustExec(t, s, `UPDATE pr_reviewers SET user_id = 'u2'`)
+	// every reassignment takes ~150ms: well within the timeout on its own,
+	// but the batch as a whole runs past it
+	mustExec(t, s, `CREATE FUNCTION slow_reviewer() RETURNS trigger AS $$
+		BEGIN PERFORM pg_sleep(0.15); RETURN NEW; END $$ LANGUAGE plpgsql`)
+	mustExec(t, s, `CREATE TRIGGER slow_reviewer BEFORE INSERT ON pr_reviewers
+		FOR EACH ROW EXECUTE FUNCTION slow_reviewer()`)
+	s.SetOpTimeout(400 * time.Millisecond)
+
+	summary, err := s.ReassignAllForUser(context.Background(), "u2")
+	if err != nil {
+		t.Fatalf("reassign all: %v", err)
+	}
+	if len(summary.Reassigned) != 4 || len(summary.Failed) != 0 {
+		t.Fatalf("summary = %+v, want all 4 PRs reassigned", summary)
+	}
+}
@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestApproveHandler(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2")
+
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1",
+	})
+	assertStatus(t, rec, http.StatusCreated)
+	if !strings.Contains(rec.Body.String(), `"approvals":[]`) {
+		t.Fatalf("created PR has no empty approvals list: %s", rec.Body.String())
+	}
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/approve", map[string]any{"pull_request_id": "pr-1", "user_id": "u2"})
+	assertStatus(t, rec, http.StatusOK)
+	var resp prResponse
+	decodeBody(t, rec, &resp)
+	if len(resp.PR.Approvals) != 1 || resp.PR.Approvals[0] != "u2" {
+		t.Fatalf("approvals = %v, want [u2]", resp.PR.Approvals)
+	}
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/approve", map[string]any{"pull_request_id": "pr-1", "user_id": "u1"})
+	assertError(t, rec, http.StatusConflict, "NOT_ASSIGNED")
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/merge", map[string]any{"pull_request_id": "pr-1"})
+	assertStatus(t, rec, http.StatusOK)
+	rec = do(t, h, http.MethodPost, "/pullRequest/approve", map[string]any{"pull_request_id": "pr-1", "user_id": "u2"})
+	assertError(t, rec, http.StatusConflict, "PR_MERGED")
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/approve", map[string]any{"pull_request_id": "pr-1"})
+	assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+}
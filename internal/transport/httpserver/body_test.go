@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOversizedBodyIsRejected(t *testing.T) {
+	srv := newOfflineServer(t)
+	srv.SetMaxBodyBytes(64)
+	h := srv.Handler()
+
+	members := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		members = append(members, fmt.Sprintf(`{"user_id":"u%d","username":"user","is_active":true}`, i))
+	}
+	body := `{"team_name":"backend","members":[` + strings.Join(members, ",") + `]}`
+
+	rec := do(t, h, http.MethodPost, "/team/add", body)
+	e := assertError(t, rec, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE")
+	if !strings.Contains(e.Error.Message, "64 bytes") {
+		t.Fatalf("message = %q, want the limit", e.Error.Message)
+	}
+}
+
+func TestUnknownFieldMatchesDecoderError(t *testing.T) {
+	var v struct {
+		Known string `json:"known"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(`{"known":"a","extra":1}`))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&v)
+	if err == nil {
+		t.Fatalf("decoder accepted an unknown field")
+	}
+	field, ok := unknownField(err)
+	if !ok || field != "extra" {
+		t.Fatalf("unknownField(%q) = %q, %v; the encoding/json message changed", err, field, ok)
+	}
+
+	if _, ok := unknownField(errors.New("unexpected EOF")); ok {
+		t.Fatalf("unknownField matched an unrelated error")
+	}
+}
+
+func TestBodyAtLimitStillDecodes(t *testing.T) {
+	srv := newOfflineServer(t)
+	// members are checked after decoding, so a 400 about them means the body
+	// got through the limit intact
+	body := `{"team_name":"backend","members":[]}`
+	srv.SetMaxBodyBytes(int64(len(body)))
+	h := srv.Handler()
+
+	rec := do(t, h, http.MethodPost, "/team/add", body)
+	e := assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	if e.Error.Message != "members must not be empty" {
+		t.Fatalf("message = %q, want the post-decode validation error", e.Error.Message)
+	}
+
+	// one byte over, inside the JSON so the decoder has to read it
+	rec = do(t, h, http.MethodPost, "/team/add", `{"team_name":"backend2","members":[]}`)
+	assertError(t, rec, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE")
+}
+
+func TestSmallPayloadWithDefaultLimit(t *testing.T) {
+	srv, _ := newTestServer(t)
+	mustAddTeam(t, srv.Handler(), "backend", "u1", "u2")
+}
+
+func TestStructuredDecodeErrors(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		code  string
+		field string
+	}{
+		{name: "extra field", path: "/pullRequest/create", body: `{"pull_request_id":"pr-1","pull_request_name":"x","author_id":"u1","reviewrs":2}`, code: "UNKNOWN_FIELD", field: "reviewrs"},
+		{name: "extra nested field", path: "/team/add", body: `{"team_name":"backend","members":[{"user_id":"u1","username":"a","is_active":true,"admin":true}]}`, code: "UNKNOWN_FIELD", field: "admin"},
+		{name: "wrong type", path: "/users/setIsActive", body: `{"user_id":"u1","is_active":"no"}`, code: "TYPE_MISMATCH", field: "is_active"},
+		{name: "wrong nested type", path: "/team/add", body: `{"team_name":"backend","members":[{"user_id":42,"username":"a","is_active":true}]}`, code: "TYPE_MISMATCH", field: "members.0.user_id"},
+		{name: "wrong type keyed by user", path: "/team/add", body: `{"team_name":"backend","members":{"u1":{"username":"a","is_active":1}}}`, code: "TYPE_MISMATCH", field: "members.u1.is_active"},
+		{name: "malformed", path: "/users/setIsActive", body: `{"user_id":`, code: "BAD_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := assertError(t, do(t, h, http.MethodPost, tt.path, tt.body), http.StatusBadRequest, tt.code)
+			if body.Error.Field != tt.field {
+				t.Fatalf("field = %q, want %q", body.Error.Field, tt.field)
+			}
+			if tt.field != "" && !strings.Contains(body.Error.Message, tt.field) {
+				t.Fatalf("message %q does not name %q", body.Error.Message, tt.field)
+			}
+		})
+	}
+}
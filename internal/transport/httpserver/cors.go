@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	corsAllowMethods = "GET, POST, OPTIONS"
+	corsAllowHeaders = "Content-Type, If-None-Match"
+	// Browsers hide non-safelisted response headers from scripts unless they
+	// are exposed explicitly.
+	corsExposeHeaders = "ETag, Location"
+	corsMaxAge        = "600"
+)
+
+type corsPolicy struct {
+	allowAll bool
+	origins  map[string]struct{}
+}
+
+func newCORSPolicy(origins []string) *corsPolicy {
+	p := &corsPolicy{origins: make(map[string]struct{}, len(origins))}
+	for _, o := range origins {
+		o = strings.TrimSpace(o)
+		switch o {
+		case "":
+		case "*":
+			p.allowAll = true
+		default:
+			p.origins[o] = struct{}{}
+		}
+	}
+	if !p.allowAll && len(p.origins) == 0 {
+		return nil
+	}
+	return p
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	_, ok := p.origins[origin]
+	return ok
+}
+
+// wrap adds CORS headers for allowed origins and answers preflight requests
+// itself. Requests from other origins pass through without CORS headers, so
+// the browser blocks them.
+func (p *corsPolicy) wrap(next http.Handler) http.Handler {
+	if p == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !p.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
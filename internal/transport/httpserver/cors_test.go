@@ -0,0 +1,72 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func doWithHeaders(h http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflight(t *testing.T) {
+	srv := newOfflineServer(t)
+	srv.SetCORSOrigins([]string{"https://dash.example.com"})
+	h := srv.Handler()
+
+	rec := doWithHeaders(h, http.MethodOptions, "/team/add", map[string]string{
+		"Origin":                        "https://dash.example.com",
+		"Access-Control-Request-Method": http.MethodPost,
+	})
+	assertStatus(t, rec, http.StatusNoContent)
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://dash.example.com",
+		"Access-Control-Allow-Methods": corsAllowMethods,
+		"Access-Control-Allow-Headers": corsAllowHeaders,
+		"Access-Control-Max-Age":       corsMaxAge,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	rec = doWithHeaders(h, http.MethodGet, "/health", map[string]string{"Origin": "https://dash.example.com"})
+	assertStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://dash.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q on a simple request", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != corsExposeHeaders {
+		t.Fatalf("Access-Control-Expose-Headers = %q", got)
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	srv := newOfflineServer(t)
+	srv.SetCORSOrigins([]string{"https://dash.example.com"})
+	h := srv.Handler()
+
+	for _, method := range []string{http.MethodOptions, http.MethodGet} {
+		rec := doWithHeaders(h, method, "/health", map[string]string{
+			"Origin":                        "https://evil.example.com",
+			"Access-Control-Request-Method": http.MethodGet,
+		})
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("%s: Access-Control-Allow-Origin = %q for a disallowed origin", method, got)
+		}
+	}
+}
+
+func TestCORSDisabledByDefault(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	rec := doWithHeaders(h, http.MethodGet, "/health", map[string]string{"Origin": "https://dash.example.com"})
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("Access-Control-Allow-Origin = %q without configured origins", got)
+	}
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeclineHandler(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1", "reviewer_count": 1,
+	})
+	assertStatus(t, rec, http.StatusCreated)
+	var created prResponse
+	decodeBody(t, rec, &created)
+	decliner := created.PR.AssignedReviewers[0]
+	other := "u2"
+	if decliner == "u2" {
+		other = "u3"
+	}
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/decline", map[string]any{"pull_request_id": "pr-1", "reviewer_id": decliner})
+	assertStatus(t, rec, http.StatusOK)
+	var resp struct {
+		prResponse
+		ReplacedBy         string `json:"replaced_by"`
+		ReplacedByUsername string `json:"replaced_by_username"`
+	}
+	decodeBody(t, rec, &resp)
+	if resp.ReplacedBy != other || resp.ReplacedByUsername != "user-"+other {
+		t.Fatalf("replaced by %s (%s), want %s", resp.ReplacedBy, resp.ReplacedByUsername, other)
+	}
+	if len(resp.PR.AssignedReviewers) != 1 || resp.PR.AssignedReviewers[0] != other {
+		t.Fatalf("reviewers after decline = %v", resp.PR.AssignedReviewers)
+	}
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/decline", map[string]any{"pull_request_id": "pr-1", "reviewer_id": decliner})
+	assertError(t, rec, http.StatusConflict, "NOT_ASSIGNED")
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/decline", map[string]any{"pull_request_id": "missing", "reviewer_id": other})
+	assertError(t, rec, http.StatusNotFound, "NOT_FOUND")
+
+	assertStatus(t, do(t, h, http.MethodPost, "/pullRequest/merge", map[string]any{"pull_request_id": "pr-1"}), http.StatusOK)
+	rec = do(t, h, http.MethodPost, "/pullRequest/decline", map[string]any{"pull_request_id": "pr-1", "reviewer_id": other})
+	assertError(t, rec, http.StatusConflict, "PR_MERGED")
+}
+
+func TestDeclineHandlerValidatesIDs(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, body := range []map[string]any{
+		{"pull_request_id": "pr 1", "reviewer_id": "u2"},
+		{"pull_request_id": "pr-1", "reviewer_id": "u/2"},
+		{"pull_request_id": "pr-1"},
+	} {
+		rec := do(t, h, http.MethodPost, "/pullRequest/decline", body)
+		assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
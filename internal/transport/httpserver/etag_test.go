@@ -0,0 +1,118 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+func TestWithETag(t *testing.T) {
+	body := `{"team_name":"backend"}`
+	h := withETag(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	})
+
+	rec := doWithHeaders(h, http.MethodGet, "/team/get", nil)
+	assertStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" || rec.Body.String() != body {
+		t.Fatalf("ETag = %q, body = %q", etag, rec.Body.String())
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		rec = doWithHeaders(h, http.MethodGet, "/team/get", map[string]string{"If-None-Match": header})
+		assertStatus(t, rec, http.StatusNotModified)
+		if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+			t.Fatalf("If-None-Match %q: 304 with body %q, Content-Type %q", header, rec.Body.String(), rec.Header().Get("Content-Type"))
+		}
+	}
+
+	// errors pass through untagged
+	failing := withETag(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	rec = httptest.NewRecorder()
+	failing(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assertStatus(t, rec, http.StatusInternalServerError)
+	if rec.Header().Get("ETag") != "" {
+		t.Fatalf("error response tagged with %q", rec.Header().Get("ETag"))
+	}
+}
+
+func TestETagRevalidation(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2")
+
+	for _, target := range []string{"/team/get?team_name=backend", "/stats"} {
+		rec := doWithHeaders(h, http.MethodGet, target, nil)
+		assertStatus(t, rec, http.StatusOK)
+		etag := rec.Header().Get("ETag")
+		if etag == "" || rec.Body.Len() == 0 {
+			t.Fatalf("%s: ETag = %q, body %d bytes", target, etag, rec.Body.Len())
+		}
+
+		rec = doWithHeaders(h, http.MethodGet, target, map[string]string{"If-None-Match": etag})
+		assertStatus(t, rec, http.StatusNotModified)
+		if rec.Body.Len() != 0 {
+			t.Fatalf("%s: 304 with body %q", target, rec.Body.String())
+		}
+		if got := rec.Header().Get("ETag"); got != etag {
+			t.Fatalf("%s: 304 ETag = %q, want %q", target, got, etag)
+		}
+
+		rec = doWithHeaders(h, http.MethodGet, target, map[string]string{"If-None-Match": `"stale"`})
+		assertStatus(t, rec, http.StatusOK)
+	}
+}
+
+func TestCORSPreflightAllowsIfNoneMatch(t *testing.T) {
+	srv := newOfflineServer(t)
+	srv.SetCORSOrigins([]string{"https://dash.example.com"})
+
+	rec := doWithHeaders(srv.Handler(), http.MethodOptions, "/team/get", map[string]string{
+		"Origin":                         "https://dash.example.com",
+		"Access-Control-Request-Method":  http.MethodGet,
+		"Access-Control-Request-Headers": "if-none-match",
+	})
+	assertStatus(t, rec, http.StatusNoContent)
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, If-None-Match" {
+		t.Fatalf("Access-Control-Allow-Headers = %q, want If-None-Match allowed", got)
+	}
+}
+
+func TestTeamETagChangesWithMembers(t *testing.T) {
+	ctx := context.Background()
+	srv, svc := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2")
+	const target = "/team/get?team_name=backend"
+
+	etag := doWithHeaders(h, http.MethodGet, target, nil).Header().Get("ETag")
+	assertStatus(t, doWithHeaders(h, http.MethodGet, target, map[string]string{"If-None-Match": etag}), http.StatusNotModified)
+
+	changes := map[string]func() error{
+		"is_active": func() error { _, err := svc.SetUserActive(ctx, "u2", false); return err },
+		"username": func() error {
+			_, err := svc.AddTeamMembers(ctx, "backend", []models.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}})
+			return err
+		},
+	}
+	for _, field := range []string{"is_active", "username"} {
+		if err := changes[field](); err != nil {
+			t.Fatalf("change %s: %v", field, err)
+		}
+		rec := doWithHeaders(h, http.MethodGet, target, map[string]string{"If-None-Match": etag})
+		assertStatus(t, rec, http.StatusOK)
+		next := rec.Header().Get("ETag")
+		if next == "" || next == etag {
+			t.Fatalf("after %s change ETag = %q, was %q", field, next, etag)
+		}
+		etag = next
+	}
+}
@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestExpandReviewers(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1",
+	})
+	assertStatus(t, rec, http.StatusCreated)
+
+	rec = do(t, h, http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var compact struct {
+		PR map[string]any `json:"pr"`
+	}
+	decodeBody(t, rec, &compact)
+	if _, ok := compact.PR["reviewers_detailed"]; ok {
+		t.Fatalf("compact response has reviewers_detailed: %v", compact.PR)
+	}
+	if _, ok := compact.PR["assigned_reviewers"]; !ok {
+		t.Fatalf("compact response lacks assigned_reviewers: %v", compact.PR)
+	}
+
+	rec = do(t, h, http.MethodGet, "/pullRequest/get?pull_request_id=pr-1&expand=reviewers", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var expanded struct {
+		PR struct {
+			AssignedReviewers []string `json:"assigned_reviewers"`
+			ReviewersDetailed []struct {
+				UserID   string `json:"user_id"`
+				Username string `json:"username"`
+				IsActive bool   `json:"is_active"`
+			} `json:"reviewers_detailed"`
+		} `json:"pr"`
+	}
+	decodeBody(t, rec, &expanded)
+	if len(expanded.PR.ReviewersDetailed) != len(expanded.PR.AssignedReviewers) {
+		t.Fatalf("detailed = %+v, assigned = %v", expanded.PR.ReviewersDetailed, expanded.PR.AssignedReviewers)
+	}
+	for _, r := range expanded.PR.ReviewersDetailed {
+		if !slices.Contains(expanded.PR.AssignedReviewers, r.UserID) || r.Username != "user-"+r.UserID || !r.IsActive {
+			t.Fatalf("unexpected reviewer detail %+v", r)
+		}
+	}
+}
+
+func TestReassignReturnsReplacementUsername(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3", "u4")
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1", "reviewer_count": 1,
+	})
+	assertStatus(t, rec, http.StatusCreated)
+	var created prResponse
+	decodeBody(t, rec, &created)
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/reassign", map[string]any{
+		"pull_request_id": "pr-1", "old_user_id": created.PR.AssignedReviewers[0],
+	})
+	assertStatus(t, rec, http.StatusOK)
+	var resp struct {
+		ReplacedBy         string `json:"replaced_by"`
+		ReplacedByUsername string `json:"replaced_by_username"`
+	}
+	decodeBody(t, rec, &resp)
+	if resp.ReplacedBy == "" || resp.ReplacedByUsername != "user-"+resp.ReplacedBy {
+		t.Fatalf("replaced_by = %q, replaced_by_username = %q", resp.ReplacedBy, resp.ReplacedByUsername)
+	}
+}
@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// minGzipSize keeps small bodies uncompressed: below about a kilobyte gzip
+// framing costs more than it saves.
+const minGzipSize = 1024
+
+// gzipWriter holds the response back until it either grows past minGzipSize,
+// at which point it switches to gzip, or the handler returns.
+type gzipWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+	gz     *gzip.Writer
+	raw    bool
+}
+
+func (g *gzipWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+func (g *gzipWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	switch {
+	case g.gz != nil:
+		return g.gz.Write(p)
+	case g.raw:
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf.Write(p)
+	if g.buf.Len() < minGzipSize {
+		return len(p), nil
+	}
+
+	h := g.Header()
+	if h.Get("Content-Encoding") != "" {
+		// Already encoded by the handler (e.g. promhttp): pass through.
+		g.raw = true
+		g.ResponseWriter.WriteHeader(g.status)
+		_, err := g.ResponseWriter.Write(g.buf.Bytes())
+		return len(p), err
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	g.ResponseWriter.WriteHeader(g.status)
+	g.gz = gzip.NewWriter(g.ResponseWriter)
+	_, err := g.gz.Write(g.buf.Bytes())
+	return len(p), err
+}
+
+func (g *gzipWriter) finish() error {
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	if g.raw {
+		return nil
+	}
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(g.buf.Bytes())
+	return err
+}
+
+func gzipResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipWriter{ResponseWriter: w}
+		next.ServeHTTP(gw, r)
+		_ = gw.finish()
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func jsonHandler(payload any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, payload)
+	})
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	var users []map[string]any
+	for i := range 200 {
+		users = append(users, map[string]any{"user_id": i, "username": strings.Repeat("x", 10), "count": i % 7})
+	}
+	payload := map[string]any{"assignments": users}
+	want, _ := json.Marshal(payload)
+	h := gzipResponses(jsonHandler(payload))
+
+	rec := doWithHeaders(h, http.MethodGet, "/stats", map[string]string{"Accept-Encoding": "br, gzip"})
+	assertStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v, want gzip encoding", rec.Header())
+	}
+	if rec.Body.Len() >= len(want) {
+		t.Fatalf("compressed body is %d bytes, plain is %d", rec.Body.Len(), len(want))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if strings.TrimSpace(string(got)) != string(want) {
+		t.Fatalf("decompressed body differs from the JSON")
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestGzipSkipped(t *testing.T) {
+	big := map[string]string{"blob": strings.Repeat("a", 4*minGzipSize)}
+	tests := []struct {
+		name    string
+		handler http.Handler
+		accept  string
+	}{
+		{name: "client without gzip", handler: jsonHandler(big)},
+		{name: "gzip refused", handler: jsonHandler(big), accept: "gzip;q=0"},
+		{name: "small body", handler: jsonHandler(map[string]string{"status": "ok"}), accept: "gzip"},
+		{name: "already encoded", handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "identity")
+			_, _ = io.WriteString(w, strings.Repeat("b", 2*minGzipSize))
+		}), accept: "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := httptest.NewRecorder()
+			tt.handler.ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			rec := doWithHeaders(gzipResponses(tt.handler), http.MethodGet, "/", map[string]string{"Accept-Encoding": tt.accept})
+			if rec.Header().Get("Content-Encoding") == "gzip" {
+				t.Fatalf("response was compressed")
+			}
+			if rec.Code != plain.Code || rec.Body.String() != plain.Body.String() {
+				t.Fatalf("got %d %q, want %d %q", rec.Code, rec.Body.String(), plain.Code, plain.Body.String())
+			}
+		})
+	}
+}
+
+func TestGzipKeepsStatus(t *testing.T) {
+	h := gzipResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	rec := doWithHeaders(h, http.MethodGet, "/team/get", map[string]string{"Accept-Encoding": "gzip"})
+	assertStatus(t, rec, http.StatusNotModified)
+	if rec.Body.Len() != 0 || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("304 with body %q, encoding %q", rec.Body.String(), rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"GZIP":              true,
+		"deflate, gzip":     true,
+		"gzip;q=0.5":        true,
+		"gzip; q=0":         false,
+		"br":                false,
+		"x-gzip-but-not-it": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(r); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestServerCompressesLargeResponses(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	plain := doWithHeaders(h, http.MethodGet, "/openapi.json", nil)
+	assertStatus(t, plain, http.StatusOK)
+
+	rec := doWithHeaders(h, http.MethodGet, "/openapi.json", map[string]string{"Accept-Encoding": "gzip"})
+	assertStatus(t, rec, http.StatusOK)
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	if string(got) != plain.Body.String() {
+		t.Fatalf("decompressed spec differs from the plain response")
+	}
+}
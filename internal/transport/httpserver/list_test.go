@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPRListValidatesQuery(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, query := range []string{
+		"status=DRAFT",
+		"sort=name",
+		"limit=0",
+		"limit=abc",
+		"limit=100000",
+		"offset=-1",
+	} {
+		rec := do(t, h, http.MethodGet, "/pullRequests?"+query, nil)
+		assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+type prListResponse struct {
+	PullRequests []struct {
+		ID       string `json:"pull_request_id"`
+		AuthorID string `json:"author_id"`
+		Status   string `json:"status"`
+	} `json:"pull_requests"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+func TestPRListFilters(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+	mustAddTeam(t, h, "frontend", "f1", "f2")
+	for id, author := range map[string]string{"b-1": "u1", "b-2": "u2", "f-1": "f1"} {
+		rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+			"pull_request_id": id, "pull_request_name": "PR " + id, "author_id": author,
+		})
+		assertStatus(t, rec, http.StatusCreated)
+	}
+	assertStatus(t, do(t, h, http.MethodPost, "/pullRequest/merge", map[string]any{"pull_request_id": "b-2"}), http.StatusOK)
+
+	tests := []struct {
+		query string
+		total int
+		ids   int
+	}{
+		{query: "", total: 3, ids: 3},
+		{query: "?team_name=backend", total: 2, ids: 2},
+		{query: "?team_name=backend&status=MERGED", total: 1, ids: 1},
+		{query: "?team_name=frontend&author_id=u1", total: 0, ids: 0},
+		{query: "?status=CLOSED", total: 0, ids: 0},
+		{query: "?limit=1&offset=1", total: 3, ids: 1},
+	}
+	for _, tt := range tests {
+		rec := do(t, h, http.MethodGet, "/pullRequests"+tt.query, nil)
+		assertStatus(t, rec, http.StatusOK)
+		var resp prListResponse
+		decodeBody(t, rec, &resp)
+		if resp.PullRequests == nil {
+			t.Fatalf("%s: pull_requests is null, want an array", tt.query)
+		}
+		if resp.Total != tt.total || len(resp.PullRequests) != tt.ids {
+			t.Fatalf("%s: total %d with %d items, want %d with %d", tt.query, resp.Total, len(resp.PullRequests), tt.total, tt.ids)
+		}
+	}
+}
+
+func TestUserReviewsCursorValidation(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, target := range []string{
+		"/users/getReview?user_id=u1&cursor=not-a-cursor",
+		"/users/getReview?user_id=u1&cursor=&offset=5",
+	} {
+		assertError(t, do(t, h, http.MethodGet, target, nil), http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestLocationEscapesIDs(t *testing.T) {
+	tests := []struct {
+		id   string
+		team string
+		pr   string
+	}{
+		{id: "backend", team: "/team/get?team_name=backend", pr: "/pullRequest/get?pull_request_id=backend"},
+		{id: "back end", team: "/team/get?team_name=back+end", pr: "/pullRequest/get?pull_request_id=back+end"},
+		{id: "a&b=c#d", team: "/team/get?team_name=a%26b%3Dc%23d", pr: "/pullRequest/get?pull_request_id=a%26b%3Dc%23d"},
+		{id: "ops/команда?", team: "/team/get?team_name=ops%2F%D0%BA%D0%BE%D0%BC%D0%B0%D0%BD%D0%B4%D0%B0%3F",
+			pr: "/pullRequest/get?pull_request_id=ops%2F%D0%BA%D0%BE%D0%BC%D0%B0%D0%BD%D0%B4%D0%B0%3F"},
+	}
+	for _, tt := range tests {
+		if got := teamLocation(tt.id); got != tt.team {
+			t.Errorf("teamLocation(%q) = %q, want %q", tt.id, got, tt.team)
+		}
+		if got := prLocation(tt.id); got != tt.pr {
+			t.Errorf("prLocation(%q) = %q, want %q", tt.id, got, tt.pr)
+		}
+		u, err := url.Parse(teamLocation(tt.id))
+		if err != nil || u.Query().Get("team_name") != tt.id {
+			t.Errorf("teamLocation(%q) does not decode back: %v", tt.id, err)
+		}
+	}
+}
+
+func TestCreatedResourcesLocationWithEscapedIDs(t *testing.T) {
+	srv, _ := newTestServer(t)
+	if err := srv.SetIDPattern(`^.{1,64}$`); err != nil {
+		t.Fatalf("set id pattern: %v", err)
+	}
+	h := srv.Handler()
+
+	rec := do(t, h, http.MethodPost, "/team/add", map[string]any{"team_name": "back end&co", "members": []teamMember{
+		{UserID: "u1", Username: "a", IsActive: true}, {UserID: "u2", Username: "b", IsActive: true},
+	}})
+	assertStatus(t, rec, http.StatusCreated)
+	loc := rec.Header().Get("Location")
+	if loc != "/team/get?team_name=back+end%26co" {
+		t.Fatalf("team Location = %q", loc)
+	}
+	assertStatus(t, do(t, h, http.MethodGet, loc, nil), http.StatusOK)
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr #1?", "pull_request_name": "x", "author_id": "u1",
+	})
+	assertStatus(t, rec, http.StatusCreated)
+	loc = rec.Header().Get("Location")
+	if loc != "/pullRequest/get?pull_request_id=pr+%231%3F" {
+		t.Fatalf("PR Location = %q", loc)
+	}
+	got := do(t, h, http.MethodGet, loc, nil)
+	assertStatus(t, got, http.StatusOK)
+	var body prResponse
+	decodeBody(t, got, &body)
+	if body.PR.ID != "pr #1?" {
+		t.Fatalf("Location resolved to PR %q", body.PR.ID)
+	}
+}
+
+func TestLocationOnlyOnCreate(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	team := map[string]any{"team_name": "backend.core", "members": []teamMember{
+		{UserID: "u1", Username: "a", IsActive: true}, {UserID: "u2", Username: "b", IsActive: true},
+	}}
+	pr := map[string]any{"pull_request_id": "pr-1", "pull_request_name": "x", "author_id": "u1"}
+
+	rec := do(t, h, http.MethodPost, "/team/add", team)
+	assertStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Location"); got != "/team/get?team_name=backend.core" {
+		t.Fatalf("team Location = %q", got)
+	}
+	rec = do(t, h, http.MethodPost, "/pullRequest/create", pr)
+	assertStatus(t, rec, http.StatusCreated)
+	if got := rec.Header().Get("Location"); got != "/pullRequest/get?pull_request_id=pr-1" {
+		t.Fatalf("PR Location = %q", got)
+	}
+
+	// a rejected duplicate points nowhere
+	for path, body := range map[string]any{"/team/add": team, "/pullRequest/create": pr} {
+		rec := do(t, h, http.MethodPost, path, body)
+		if rec.Code < 400 || rec.Header().Get("Location") != "" {
+			t.Fatalf("%s duplicate: status %d, Location %q", path, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+}
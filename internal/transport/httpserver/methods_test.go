@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// getRoutes and postRoutes list every JSON endpoint by the method it accepts.
+var getRoutes = []string{
+	"/health", "/ready", "/version", "/team/get",
+	"/pullRequest/get", "/pullRequest/history", "/pullRequest/list", "/pullRequests",
+	"/users/getReview", "/stats", "/events", "/audit", "/debug/pool", "/openapi.json",
+}
+
+var postRoutes = []string{
+	"/team/add", "/team/addMembers", "/team/updateMember", "/team/rename", "/team/link",
+	"/team/delete", "/team/archive", "/team/setReviewerCount",
+	"/users/setIsActive", "/users/setMaxReviews", "/users/setUnavailable", "/users/reassignAll",
+	"/pullRequest/create", "/pullRequest/merge", "/pullRequest/close", "/pullRequest/delete",
+	"/pullRequest/reassign", "/pullRequest/decline", "/pullRequest/approve",
+	"/pullRequest/requestChanges", "/pullRequest/reconcile",
+}
+
+func TestWrongMethodReturns405WithAllow(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+
+	check := func(path, method, allow string) {
+		t.Run(method+" "+path, func(t *testing.T) {
+			rec := do(t, h, method, path, nil)
+			assertError(t, rec, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+			if got := rec.Header().Get("Allow"); got != allow {
+				t.Fatalf("Allow = %q, want %q", got, allow)
+			}
+		})
+	}
+	for _, path := range getRoutes {
+		check(path, http.MethodPost, http.MethodGet)
+	}
+	for _, path := range postRoutes {
+		check(path, http.MethodGet, http.MethodPost)
+	}
+}
+
+func TestMethodNotAllowedBody(t *testing.T) {
+	rec := httptest.NewRecorder()
+	methodNotAllowed(rec, http.MethodGet, http.MethodPost)
+
+	e := assertError(t, rec, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+	if got := rec.Header().Get("Allow"); got != "GET, POST" {
+		t.Fatalf("Allow = %q, want %q", got, "GET, POST")
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if e.Error.Message != "method not allowed, use GET or POST" {
+		t.Fatalf("message = %q", e.Error.Message)
+	}
+}
@@ -0,0 +1,112 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	// logRequests writes a line per request; see Server.SetRequestLogging.
+	logRequests bool
+}
+
+func newMetrics(svc *service.Service, registry *prometheus.Registry) *metrics {
+	m := &metrics{
+		registry: registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Number of handled HTTP requests.",
+		}, []string{"path", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"path", "method", "status"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		// Counted on scrape, so the value is never stale and constructing a
+		// server runs no queries; CountActiveUsers bounds the query itself.
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "active_users",
+			Help: "Number of users eligible for review assignment.",
+		}, func() float64 {
+			n, err := svc.CountActiveUsers(context.Background())
+			if err != nil {
+				log.Printf("count active users metric: %v", err)
+				return math.NaN()
+			}
+			return float64(n)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "db_open_connections",
+			Help: "Number of established database connections.",
+		}, func() float64 {
+			return float64(svc.DBStats().OpenConnections)
+		}),
+	)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (m *metrics) instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		mux.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+
+		_, pattern := mux.Handler(r)
+		path := handlerName(pattern)
+		status := strconv.Itoa(rec.status)
+		m.requests.WithLabelValues(path, r.Method, status).Inc()
+		m.latency.WithLabelValues(path, r.Method, status).Observe(elapsed.Seconds())
+		if m.logRequests {
+			log.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, elapsed)
+		}
+	})
+}
+
+// handlerName turns a mux pattern into a metric label: /pullRequest/create
+// becomes pullRequest_create, and unmatched routes are "unknown".
+func handlerName(pattern string) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(pattern, "/"), "/", "_")
+	if name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
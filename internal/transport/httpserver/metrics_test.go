@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+func TestMetricsCountRequests(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for i := 0; i < 3; i++ {
+		assertStatus(t, do(t, h, http.MethodGet, "/health", nil), http.StatusOK)
+	}
+	do(t, h, http.MethodPost, "/health", nil)
+	do(t, h, http.MethodGet, "/no/such/route", nil)
+
+	rec := do(t, h, http.MethodGet, "/metrics", nil)
+	assertStatus(t, rec, http.StatusOK)
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",path="health",status="200"} 3`,
+		`http_requests_total{method="POST",path="health",status="405"} 1`,
+		`http_request_duration_seconds_count{method="GET",path="health",status="200"} 3`,
+		`http_requests_total{method="GET",path="unknown",status="404"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/pullRequest/create": "pullRequest_create",
+		"/health":             "health",
+		"":                    "unknown",
+	} {
+		if got := handlerName(pattern); got != want {
+			t.Errorf("handlerName(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestMetricsUseInjectedRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	h := NewWithRegistry(service.New(offlineDB(t)), registry).Handler()
+
+	requests := func() float64 {
+		families, err := registry.Gather()
+		if err != nil {
+			t.Fatalf("gather: %v", err)
+		}
+		var total float64
+		for _, f := range families {
+			if f.GetName() != "http_requests_total" {
+				continue
+			}
+			for _, m := range f.GetMetric() {
+				total += m.GetCounter().GetValue()
+			}
+		}
+		return total
+	}
+
+	before := requests()
+	do(t, h, http.MethodGet, "/version", nil)
+	if after := requests(); after != before+1 {
+		t.Fatalf("http_requests_total went from %v to %v, want +1", before, after)
+	}
+	assertStatus(t, do(t, h, http.MethodGet, "/metrics", nil), http.StatusOK)
+}
+
+func TestActiveUsersCountedOnScrape(t *testing.T) {
+	srv, svc := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+
+	scrape := func() string {
+		rec := do(t, h, http.MethodGet, "/metrics", nil)
+		assertStatus(t, rec, http.StatusOK)
+		return rec.Body.String()
+	}
+	if body := scrape(); !strings.Contains(body, "\nactive_users 3\n") {
+		t.Fatalf("metrics do not report 3 active users")
+	}
+	// a change made past the HTTP handlers shows up on the next scrape
+	if _, err := svc.SetUserActive(context.Background(), "u2", false); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if body := scrape(); !strings.Contains(body, "\nactive_users 2\n") {
+		t.Fatalf("metrics do not report 2 active users")
+	}
+}
+
+func TestActiveUsersUnavailableDB(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	rec := do(t, h, http.MethodGet, "/metrics", nil)
+	assertStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "\nactive_users NaN\n") {
+		t.Fatalf("metrics do not report NaN active users without a database")
+	}
+}
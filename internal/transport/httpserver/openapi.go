@@ -0,0 +1,36 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	assignment "github.com/123jjck/avito-trainee-assignment"
+)
+
+// openAPIJSON converts the embedded openapi.yml once, on first request.
+var openAPIJSON = sync.OnceValues(func() ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(assignment.OpenAPISpec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi.yml: %w", err)
+	}
+	return json.Marshal(doc)
+})
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	spec, err := openAPIJSON()
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(spec)
+}
@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	rec := do(t, h, http.MethodGet, "/openapi.json", nil)
+	assertStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	decodeBody(t, rec, &spec)
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want a 3.x document", spec.OpenAPI)
+	}
+
+	check := func(path, method string) {
+		ops, ok := spec.Paths[path]
+		if !ok {
+			t.Errorf("%s is not documented", path)
+			return
+		}
+		if _, ok := ops[method]; !ok {
+			t.Errorf("%s has no %s operation", path, method)
+		}
+	}
+	for _, path := range getRoutes {
+		check(path, "get")
+	}
+	for _, path := range postRoutes {
+		check(path, "post")
+	}
+	check("/metrics", "get")
+	if want := len(getRoutes) + len(postRoutes) + 1; len(spec.Paths) != want {
+		t.Errorf("spec documents %d paths, the server serves %d", len(spec.Paths), want)
+	}
+}
@@ -0,0 +1,29 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+func TestDebugPoolFields(t *testing.T) {
+	conn := offlineDB(t)
+	conn.SetMaxOpenConns(7)
+	h := New(service.New(conn)).Handler()
+
+	rec := do(t, h, http.MethodGet, "/debug/pool", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var body map[string]any
+	decodeBody(t, rec, &body)
+	for _, field := range []string{
+		"max_open_connections", "open_connections", "in_use", "idle", "wait_count", "wait_duration_seconds",
+	} {
+		if _, ok := body[field].(float64); !ok {
+			t.Errorf("%s = %#v, want a number", field, body[field])
+		}
+	}
+	if body["max_open_connections"] != float64(7) {
+		t.Fatalf("max_open_connections = %v, want 7", body["max_open_connections"])
+	}
+}
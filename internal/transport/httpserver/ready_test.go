@@ -0,0 +1,102 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/db"
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+func TestReadyHandler(t *testing.T) {
+	srv := newOfflineServer(t)
+
+	srv.ping = func(context.Context) error { return nil }
+	rec := do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var ok struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, rec, &ok)
+	if ok.Status != "ok" {
+		t.Fatalf("status = %q, want ok", ok.Status)
+	}
+
+	srv.ping = func(context.Context) error { return errors.New("connection refused") }
+	rec = do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertError(t, rec, http.StatusServiceUnavailable, "UNAVAILABLE")
+
+	// liveness does not depend on the database
+	assertStatus(t, do(t, srv.Handler(), http.MethodGet, "/health", nil), http.StatusOK)
+}
+
+func TestReadyWithClosedDB(t *testing.T) {
+	conn := offlineDB(t)
+	conn.Close()
+	h := New(service.New(conn)).Handler()
+
+	rec := do(t, h, http.MethodGet, "/ready", nil)
+	assertError(t, rec, http.StatusServiceUnavailable, "UNAVAILABLE")
+	var body struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Status != "unavailable" {
+		t.Fatalf("status = %q, want unavailable", body.Status)
+	}
+}
+
+type readyBody struct {
+	Status         string `json:"status"`
+	SchemaVersion  int    `json:"schema_version"`
+	ExpectedSchema int    `json:"expected_schema_version"`
+	SchemaOutdated bool   `json:"schema_outdated"`
+}
+
+func TestReadyReportsSchemaVersion(t *testing.T) {
+	srv := newOfflineServer(t)
+	srv.ping = func(context.Context) error { return nil }
+
+	version := 3
+	srv.SetSchemaVersion(func(context.Context) (int, error) { return version, nil }, 3)
+	rec := do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var body readyBody
+	decodeBody(t, rec, &body)
+	if body.Status != "ok" || body.SchemaVersion != 3 || body.ExpectedSchema != 3 || body.SchemaOutdated {
+		t.Fatalf("body = %+v, want version 3 of 3, up to date", body)
+	}
+
+	// a missed migration keeps the service ready but flags it
+	version = 2
+	rec = do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertStatus(t, rec, http.StatusOK)
+	body = readyBody{}
+	decodeBody(t, rec, &body)
+	if body.SchemaVersion != 2 || !body.SchemaOutdated {
+		t.Fatalf("body = %+v, want version 2 flagged as outdated", body)
+	}
+
+	srv.SetSchemaVersion(func(context.Context) (int, error) { return 0, errors.New("relation does not exist") }, 3)
+	rec = do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertError(t, rec, http.StatusServiceUnavailable, "UNAVAILABLE")
+}
+
+func TestReadySchemaVersionAfterMigrations(t *testing.T) {
+	conn := dbtest.Open(t)
+	srv := New(service.New(conn))
+	srv.SetSchemaVersion(func(ctx context.Context) (int, error) {
+		return db.SchemaVersion(ctx, conn)
+	}, db.LatestVersion())
+
+	rec := do(t, srv.Handler(), http.MethodGet, "/ready", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var body readyBody
+	decodeBody(t, rec, &body)
+	if body.SchemaVersion != db.LatestVersion() || body.ExpectedSchema != db.LatestVersion() || body.SchemaOutdated {
+		t.Fatalf("body = %+v, want version %d, up to date", body, db.LatestVersion())
+	}
+}
@@ -1,54 +1,245 @@
 package httpserver
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/buildinfo"
 	"github.com/123jjck/avito-trainee-assignment/internal/models"
 	"github.com/123jjck/avito-trainee-assignment/internal/service"
 )
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+const readyTimeout = 2 * time.Second
+
+const defaultMaxBodyBytes = 1 << 20
+
+const defaultRequestTimeout = 5 * time.Second
+
+var defaultIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
 type Server struct {
-	svc *service.Service
-	mux *http.ServeMux
+	svc       *service.Service
+	mux       *http.ServeMux
+	metrics   *metrics
+	ping      func(context.Context) error
+	idPattern *regexp.Regexp
+	// schemaVersion and expectedSchema are reported by /ready when set.
+	schemaVersion  func(context.Context) (int, error)
+	expectedSchema int
+	cors           *corsPolicy
+	maxBodyBytes   int64
+	timeout        time.Duration
 }
 
 func New(svc *service.Service) *Server {
+	return NewWithRegistry(svc, prometheus.NewRegistry())
+}
+
+func NewWithRegistry(svc *service.Service, registry *prometheus.Registry) *Server {
 	s := &Server{
-		svc: svc,
-		mux: http.NewServeMux(),
+		svc:          svc,
+		mux:          http.NewServeMux(),
+		metrics:      newMetrics(svc, registry),
+		ping:         svc.Ping,
+		idPattern:    defaultIDPattern,
+		maxBodyBytes: defaultMaxBodyBytes,
+		timeout:      defaultRequestTimeout,
 	}
 
 	s.mux.HandleFunc("/health", s.healthHandler)
+	s.mux.HandleFunc("/ready", s.readyHandler)
+	s.mux.HandleFunc("/version", s.versionHandler)
 	s.mux.HandleFunc("/team/add", s.teamAddHandler)
-	s.mux.HandleFunc("/team/get", s.teamGetHandler)
+	s.mux.HandleFunc("/team/get", withETag(s.teamGetHandler))
+	s.mux.HandleFunc("/team/addMembers", s.teamAddMembersHandler)
+	s.mux.HandleFunc("/team/updateMember", s.teamUpdateMemberHandler)
+	s.mux.HandleFunc("/team/rename", s.teamRenameHandler)
+	s.mux.HandleFunc("/team/link", s.teamLinkHandler)
+	s.mux.HandleFunc("/team/delete", s.teamDeleteHandler)
+	s.mux.HandleFunc("/team/archive", s.teamArchiveHandler)
+	s.mux.HandleFunc("/team/setReviewerCount", s.teamSetReviewerCountHandler)
 	s.mux.HandleFunc("/users/setIsActive", s.setActiveHandler)
+	s.mux.HandleFunc("/users/setMaxReviews", s.setMaxReviewsHandler)
+	s.mux.HandleFunc("/users/setUnavailable", s.setUnavailableHandler)
+	s.mux.HandleFunc("/users/reassignAll", s.reassignAllHandler)
 	s.mux.HandleFunc("/pullRequest/create", s.prCreateHandler)
+	s.mux.HandleFunc("/pullRequest/get", s.prGetHandler)
 	s.mux.HandleFunc("/pullRequest/merge", s.prMergeHandler)
+	s.mux.HandleFunc("/pullRequest/close", s.prCloseHandler)
+	s.mux.HandleFunc("/pullRequest/delete", s.prDeleteHandler)
 	s.mux.HandleFunc("/pullRequest/reassign", s.prReassignHandler)
+	s.mux.HandleFunc("/pullRequest/decline", s.prDeclineHandler)
+	s.mux.HandleFunc("/pullRequest/approve", s.prApproveHandler)
+	s.mux.HandleFunc("/pullRequest/requestChanges", s.prRequestChangesHandler)
+	s.mux.HandleFunc("/pullRequest/reconcile", s.prReconcileHandler)
+	s.mux.HandleFunc("/pullRequest/history", s.prHistoryHandler)
+	s.mux.HandleFunc("/pullRequest/list", s.prListHandler)
+	s.mux.HandleFunc("/pullRequests", s.prListHandler)
 	s.mux.HandleFunc("/users/getReview", s.userReviewsHandler)
-	s.mux.HandleFunc("/stats", s.statsHandler)
+	s.mux.HandleFunc("/stats", withETag(s.statsHandler))
+	s.mux.HandleFunc("/events", s.eventsHandler)
+	s.mux.HandleFunc("/audit", s.auditHandler)
+	s.mux.Handle("/metrics", s.metrics.handler())
+	s.mux.HandleFunc("/debug/pool", s.debugPoolHandler)
+	s.mux.HandleFunc("/openapi.json", s.openAPIHandler)
 
 	return s
 }
 
+// SetSchemaVersion makes /ready report the applied schema version next to
+// the one this build expects.
+func (s *Server) SetSchemaVersion(current func(context.Context) (int, error), expected int) {
+	s.schemaVersion = current
+	s.expectedSchema = expected
+}
+
+func (s *Server) SetIDPattern(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("compile id pattern: %w", err)
+	}
+	s.idPattern = re
+	return nil
+}
+
+// SetCORSOrigins enables CORS for the given origins ("*" allows any); an
+// empty list keeps CORS disabled.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.cors = newCORSPolicy(origins)
+}
+
+func (s *Server) SetMaxBodyBytes(n int64) {
+	s.maxBodyBytes = n
+}
+
+// SetRequestTimeout bounds the context handlers pass to the service; zero
+// disables the limit.
+func (s *Server) SetRequestTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// SetRequestLogging makes the server log every request with its status and
+// latency. It is off by default: the metrics already cover the traffic.
+func (s *Server) SetRequestLogging(v bool) {
+	s.metrics.logRequests = v
+}
+
 func (s *Server) Handler() http.Handler {
-	return s.mux
+	return s.cors.wrap(s.limitBody(s.withTimeout(gzipResponses(s.metrics.instrument(s.mux)))))
+}
+
+func (s *Server) withTimeout(next http.Handler) http.Handler {
+	if s.timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
 }
 
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+func (s *Server) versionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := s.ping(ctx); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "unavailable",
+			"error": map[string]any{
+				"code":    "UNAVAILABLE",
+				"message": "database is unreachable: " + err.Error(),
+			},
+		})
+		return
+	}
+	if s.schemaVersion == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+	version, err := s.schemaVersion(ctx)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status": "unavailable",
+			"error": map[string]any{
+				"code":    "UNAVAILABLE",
+				"message": err.Error(),
+			},
+		})
+		return
+	}
+	resp := map[string]any{
+		"status":                  "ok",
+		"schema_version":          version,
+		"expected_schema_version": s.expectedSchema,
+	}
+	if version < s.expectedSchema {
+		// Still ready to serve, but the deploy did not run every migration.
+		resp["schema_outdated"] = true
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) debugPoolHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	st := s.svc.DBStats()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"max_open_connections":  st.MaxOpenConnections,
+		"open_connections":      st.OpenConnections,
+		"in_use":                st.InUse,
+		"idle":                  st.Idle,
+		"wait_count":            st.WaitCount,
+		"wait_duration_seconds": st.WaitDuration.Seconds(),
+	})
+}
+
 func (s *Server) teamAddHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
 	var req models.Team
@@ -56,7 +247,7 @@ func (s *Server) teamAddHandler(w http.ResponseWriter, r *http.Request) {
 		writeDecodeError(w, err)
 		return
 	}
-	teamReq, err := sanitizeTeam(req)
+	teamReq, err := s.sanitizeTeam(req)
 	if err != nil {
 		writeDecodeError(w, err)
 		return
@@ -67,12 +258,13 @@ func (s *Server) teamAddHandler(w http.ResponseWriter, r *http.Request) {
 		writeAppError(w, err)
 		return
 	}
+	w.Header().Set("Location", teamLocation(team.TeamName))
 	writeJSON(w, http.StatusCreated, map[string]any{"team": team})
 }
 
 func (s *Server) teamGetHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodGet)
 		return
 	}
 	teamName := r.URL.Query().Get("team_name")
@@ -80,7 +272,10 @@ func (s *Server) teamGetHandler(w http.ResponseWriter, r *http.Request) {
 		writeDecodeError(w, errors.New("team_name query parameter is required"))
 		return
 	}
-	team, err := s.svc.GetTeam(r.Context(), teamName)
+	team, err := s.svc.GetTeam(r.Context(), teamName, service.GetTeamOptions{
+		IncludeArchived: r.URL.Query().Get("include_archived") == "true",
+		ActiveOnly:      r.URL.Query().Get("active_only") == "true",
+	})
 	if err != nil {
 		writeAppError(w, err)
 		return
@@ -88,212 +283,1218 @@ func (s *Server) teamGetHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, team)
 }
 
-func (s *Server) setActiveHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) teamAddMembersHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
-	var req struct {
-		UserID   string `json:"user_id"`
-		IsActive bool   `json:"is_active"`
-	}
+	var req models.Team
 	if err := decodeJSON(r, &req); err != nil {
 		writeDecodeError(w, err)
 		return
 	}
-	req.UserID = strings.TrimSpace(req.UserID)
-	if req.UserID == "" {
-		writeDecodeError(w, errors.New("user_id is required"))
+	teamReq, err := s.sanitizeTeam(req)
+	if err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	user, err := s.svc.SetUserActive(r.Context(), req.UserID, req.IsActive)
+	team, err := s.svc.AddTeamMembers(r.Context(), teamReq.TeamName, teamReq.Members)
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
 }
 
-func (s *Server) prCreateHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) teamUpdateMemberHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
 	var req struct {
-		ID     string `json:"pull_request_id"`
-		Name   string `json:"pull_request_name"`
-		Author string `json:"author_id"`
+		TeamName string  `json:"team_name"`
+		UserID   string  `json:"user_id"`
+		Username *string `json:"username"`
+		IsActive *bool   `json:"is_active"`
 	}
 	if err := decodeJSON(r, &req); err != nil {
 		writeDecodeError(w, err)
 		return
 	}
-	req.ID = strings.TrimSpace(req.ID)
-	req.Name = strings.TrimSpace(req.Name)
-	req.Author = strings.TrimSpace(req.Author)
-	if req.ID == "" || req.Name == "" || req.Author == "" {
-		writeDecodeError(w, errors.New("pull_request_id, pull_request_name and author_id are required"))
+	req.TeamName = strings.TrimSpace(req.TeamName)
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.TeamName == "" || req.UserID == "" {
+		writeDecodeError(w, errors.New("team_name and user_id are required"))
+		return
+	}
+	if req.Username == nil && req.IsActive == nil {
+		writeDecodeError(w, errors.New("username or is_active is required"))
 		return
 	}
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if username == "" {
+			writeDecodeError(w, errors.New("username must not be empty"))
+			return
+		}
+		req.Username = &username
+	}
 
-	pr, err := s.svc.CreatePullRequest(r.Context(), service.CreatePRInput{
-		ID:     req.ID,
-		Name:   req.Name,
-		Author: req.Author,
+	user, err := s.svc.UpdateTeamMember(r.Context(), req.TeamName, service.MemberUpdate{
+		UserID:   req.UserID,
+		Username: req.Username,
+		IsActive: req.IsActive,
 	})
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusCreated, map[string]any{"pr": pr})
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
 }
 
-func (s *Server) prMergeHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) teamRenameHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
 	var req struct {
-		ID string `json:"pull_request_id"`
+		OldName string `json:"old_name"`
+		NewName string `json:"new_name"`
 	}
 	if err := decodeJSON(r, &req); err != nil {
 		writeDecodeError(w, err)
 		return
 	}
-	req.ID = strings.TrimSpace(req.ID)
-	if req.ID == "" {
-		writeDecodeError(w, errors.New("pull_request_id is required"))
+	req.OldName = strings.TrimSpace(req.OldName)
+	req.NewName = strings.TrimSpace(req.NewName)
+	if req.OldName == "" || req.NewName == "" {
+		writeDecodeError(w, errors.New("old_name and new_name are required"))
+		return
+	}
+	if err := s.validateID("new_name", req.NewName); err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
-	pr, err := s.svc.MergePullRequest(r.Context(), req.ID)
+	team, err := s.svc.RenameTeam(r.Context(), req.OldName, req.NewName)
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"pr": pr})
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
 }
 
-func (s *Server) prReassignHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) teamLinkHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
 	var req struct {
-		PRID     string `json:"pull_request_id"`
-		OldUser  string `json:"old_user_id"`
-		AltField string `json:"old_reviewer_id"`
+		TeamName   string `json:"team_name"`
+		LinkedTeam string `json:"linked_team_name"`
 	}
 	if err := decodeJSON(r, &req); err != nil {
 		writeDecodeError(w, err)
 		return
 	}
-	if req.OldUser == "" && req.AltField != "" {
-		req.OldUser = req.AltField // support example field name
+	req.TeamName = strings.TrimSpace(req.TeamName)
+	req.LinkedTeam = strings.TrimSpace(req.LinkedTeam)
+	if req.TeamName == "" || req.LinkedTeam == "" {
+		writeDecodeError(w, errors.New("team_name and linked_team_name are required"))
+		return
 	}
-	req.PRID = strings.TrimSpace(req.PRID)
-	req.OldUser = strings.TrimSpace(req.OldUser)
-	if req.PRID == "" || req.OldUser == "" {
-		writeDecodeError(w, errors.New("pull_request_id and old_user_id are required"))
+	if req.TeamName == req.LinkedTeam {
+		writeDecodeError(w, errors.New("team cannot be linked to itself"))
 		return
 	}
 
-	pr, replacedBy, err := s.svc.ReassignReviewer(r.Context(), req.PRID, req.OldUser)
-	if err != nil {
+	if err := s.svc.LinkTeams(r.Context(), req.TeamName, req.LinkedTeam); err != nil {
 		writeAppError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"pr": pr, "replaced_by": replacedBy})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"team_name":        req.TeamName,
+		"linked_team_name": req.LinkedTeam,
+	})
 }
 
-func (s *Server) userReviewsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+func (s *Server) teamDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
-	userID := r.URL.Query().Get("user_id")
-	userID = strings.TrimSpace(userID)
-	if userID == "" {
-		writeDecodeError(w, errors.New("user_id query parameter is required"))
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.TeamName = strings.TrimSpace(req.TeamName)
+	if req.TeamName == "" {
+		writeDecodeError(w, errors.New("team_name is required"))
 		return
 	}
 
-	prs, err := s.svc.ListUserReviews(r.Context(), userID)
+	deleted, err := s.svc.DeleteTeam(r.Context(), req.TeamName)
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"user_id":       userID,
-		"pull_requests": prs,
+		"team_name":     req.TeamName,
+		"deleted_users": deleted,
 	})
 }
 
-func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+func (s *Server) teamArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.TeamName = strings.TrimSpace(req.TeamName)
+	if req.TeamName == "" {
+		writeDecodeError(w, errors.New("team_name is required"))
 		return
 	}
-	stats, err := s.svc.Stats(r.Context())
+
+	team, err := s.svc.ArchiveTeam(r.Context(), req.TeamName)
 	if err != nil {
 		writeAppError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, stats)
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
 }
 
-func decodeJSON(r *http.Request, v any) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	return decoder.Decode(v)
+func (s *Server) teamSetReviewerCountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		TeamName      string `json:"team_name"`
+		ReviewerCount int    `json:"reviewer_count"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.TeamName = strings.TrimSpace(req.TeamName)
+	if req.TeamName == "" {
+		writeDecodeError(w, errors.New("team_name is required"))
+		return
+	}
+	if req.ReviewerCount <= 0 {
+		writeDecodeError(w, errors.New("reviewer_count must be positive"))
+		return
+	}
+
+	team, err := s.svc.SetTeamReviewerCount(r.Context(), req.TeamName, req.ReviewerCount)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"team": team})
 }
 
-func sanitizeTeam(team models.Team) (models.Team, error) {
-	team.TeamName = strings.TrimSpace(team.TeamName)
-	if team.TeamName == "" {
-		return models.Team{}, errors.New("team_name is required")
+func (s *Server) setActiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
 	}
-	if len(team.Members) == 0 {
-		return models.Team{}, errors.New("members must not be empty")
+	var req struct {
+		UserID       string `json:"user_id"`
+		IsActive     bool   `json:"is_active"`
+		ReassignOpen bool   `json:"reassign_open"`
 	}
-	for i, m := range team.Members {
-		m.UserID = strings.TrimSpace(m.UserID)
-		m.Username = strings.TrimSpace(m.Username)
-		if m.UserID == "" || m.Username == "" {
-			return models.Team{}, errors.New("member user_id and username are required")
-		}
-		team.Members[i] = m
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
 	}
-	return team, nil
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		writeDecodeError(w, errors.New("user_id is required"))
+		return
+	}
+	if err := s.validateID("user_id", req.UserID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	user, err := s.svc.SetUserActive(r.Context(), req.UserID, req.IsActive)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if req.IsActive || !req.ReassignOpen {
+		writeJSON(w, http.StatusOK, map[string]any{"user": user})
+		return
+	}
+
+	// runs after the deactivation is committed, so the user is no longer a
+	// candidate for any of the replacements
+	summary, err := s.svc.ReassignAllForUser(r.Context(), req.UserID)
+	resp := map[string]any{"user": user, "reassignment": summary}
+	if err != nil {
+		// The deactivation is already committed, so this is not a failed
+		// request: report what was reassigned before the error.
+		_, resp["reassignment_error"] = appErrorPayload(err)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
-func writeDecodeError(w http.ResponseWriter, err error) {
-	writeJSON(w, http.StatusBadRequest, map[string]any{
-		"error": map[string]any{
-			"code":    "BAD_REQUEST",
-			"message": err.Error(),
-		},
+func (s *Server) reassignAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		writeDecodeError(w, errors.New("user_id is required"))
+		return
+	}
+
+	summary, err := s.svc.ReassignAllForUser(r.Context(), req.UserID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user_id":    req.UserID,
+		"reassigned": summary.Reassigned,
+		"failed":     summary.Failed,
 	})
 }
 
-func writeAppError(w http.ResponseWriter, err error) {
-	var appErr *service.AppError
-	if errors.As(err, &appErr) {
-		writeJSON(w, appErr.Status, map[string]any{
-			"error": map[string]any{
-				"code":    appErr.Code,
-				"message": appErr.Message,
-			},
-		})
+func (s *Server) setMaxReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
 		return
 	}
-	writeJSON(w, http.StatusInternalServerError, map[string]any{
-		"error": map[string]any{
-			"code":    "INTERNAL",
-			"message": err.Error(),
-		},
-	})
+	var req struct {
+		UserID         string `json:"user_id"`
+		MaxOpenReviews *int   `json:"max_open_reviews"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		writeDecodeError(w, errors.New("user_id is required"))
+		return
+	}
+	if req.MaxOpenReviews != nil && *req.MaxOpenReviews < 0 {
+		writeDecodeError(w, errors.New("max_open_reviews must not be negative"))
+		return
+	}
+
+	user, err := s.svc.SetUserMaxOpenReviews(r.Context(), req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (s *Server) setUnavailableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		UserID string     `json:"user_id"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.UserID == "" {
+		writeDecodeError(w, errors.New("user_id is required"))
+		return
+	}
+	var until time.Time
+	if req.Until != nil {
+		until = *req.Until
+	}
+
+	user, err := s.svc.SetUserUnavailable(r.Context(), req.UserID, until)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"user": user})
+}
+
+func (s *Server) prCreateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		ID                   string `json:"pull_request_id"`
+		Name                 string `json:"pull_request_name"`
+		Author               string `json:"author_id"`
+		ReviewerCount        int    `json:"reviewer_count"`
+		MinReviewers         int    `json:"min_reviewers"`
+		CrossTeam            bool   `json:"cross_team"`
+		AllowSelfReview      bool   `json:"allow_self_review"`
+		Role                 string `json:"role"`
+		Diversify            bool   `json:"diversify"`
+		AvoidRepeatReviewers bool   `json:"avoid_repeat_reviewers"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Author = strings.TrimSpace(req.Author)
+	if req.ID == "" || req.Name == "" || req.Author == "" {
+		writeDecodeError(w, errors.New("pull_request_id, pull_request_name and author_id are required"))
+		return
+	}
+	if err := s.validateID("pull_request_id", req.ID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := s.validateID("author_id", req.Author); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.MinReviewers < 0 {
+		writeDecodeError(w, errors.New("min_reviewers must be non-negative"))
+		return
+	}
+
+	pr, err := s.svc.CreatePullRequest(r.Context(), service.CreatePRInput{
+		ID:                   req.ID,
+		Name:                 req.Name,
+		Author:               req.Author,
+		ReviewerCount:        req.ReviewerCount,
+		MinReviewers:         req.MinReviewers,
+		CrossTeam:            req.CrossTeam,
+		AllowSelfReview:      req.AllowSelfReview,
+		Role:                 strings.TrimSpace(req.Role),
+		Diversify:            req.Diversify,
+		AvoidRepeatReviewers: req.AvoidRepeatReviewers,
+	})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	w.Header().Set("Location", prLocation(pr.ID))
+	writeJSON(w, http.StatusCreated, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prGetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	prID := strings.TrimSpace(r.URL.Query().Get("pull_request_id"))
+	if prID == "" {
+		writeDecodeError(w, errors.New("pull_request_id query parameter is required"))
+		return
+	}
+
+	pr, err := s.svc.GetPullRequest(r.Context(), prID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prMergeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		ID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" {
+		writeDecodeError(w, errors.New("pull_request_id is required"))
+		return
+	}
+
+	pr, err := s.svc.MergePullRequest(r.Context(), req.ID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prCloseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		ID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" {
+		writeDecodeError(w, errors.New("pull_request_id is required"))
+		return
+	}
+
+	pr, err := s.svc.ClosePullRequest(r.Context(), req.ID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID  string `json:"pull_request_id"`
+		Force bool   `json:"force"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	if req.PRID == "" {
+		writeDecodeError(w, errors.New("pull_request_id is required"))
+		return
+	}
+
+	pr, err := s.svc.DeletePullRequest(r.Context(), req.PRID, req.Force)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prReassignHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID     string   `json:"pull_request_id"`
+		OldUser  string   `json:"old_user_id"`
+		AltField string   `json:"old_reviewer_id"`
+		OldUsers []string `json:"old_user_ids"`
+		NewUser  string   `json:"new_user_id"`
+		Actor    string   `json:"actor_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if req.OldUser == "" && req.AltField != "" {
+		req.OldUser = req.AltField // support example field name
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	req.OldUser = strings.TrimSpace(req.OldUser)
+	req.NewUser = strings.TrimSpace(req.NewUser)
+	req.Actor = strings.TrimSpace(req.Actor)
+	if req.Actor != "" {
+		if err := s.validateID("actor_id", req.Actor); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+	}
+	opts := service.ReassignOptions{ActorID: req.Actor}
+	if len(req.OldUsers) > 0 {
+		if req.NewUser != "" {
+			writeDecodeError(w, errors.New("new_user_id cannot be combined with old_user_ids"))
+			return
+		}
+		s.reassignMany(w, r, req.PRID, req.OldUser, req.OldUsers, opts)
+		return
+	}
+	if req.PRID == "" || req.OldUser == "" {
+		writeDecodeError(w, errors.New("pull_request_id and old_user_id are required"))
+		return
+	}
+	if err := s.validateID("pull_request_id", req.PRID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := s.validateID("old_user_id", req.OldUser); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if req.NewUser != "" {
+		if err := s.validateID("new_user_id", req.NewUser); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		opts.Targets = map[string]string{req.OldUser: req.NewUser}
+	}
+
+	pr, entries, err := s.svc.ReassignReviewersWith(r.Context(), req.PRID, []string{req.OldUser}, opts)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	replacedBy := entries[0].NewUserID
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pr":                   expandPR(r, pr),
+		"replaced_by":          replacedBy,
+		"replaced_by_username": reviewerUsername(pr, replacedBy),
+	})
+}
+
+func (s *Server) reassignMany(w http.ResponseWriter, r *http.Request, prID, oldUser string, oldUsers []string, opts service.ReassignOptions) {
+	if oldUser != "" {
+		writeDecodeError(w, errors.New("old_user_id and old_user_ids are mutually exclusive"))
+		return
+	}
+	if prID == "" {
+		writeDecodeError(w, errors.New("pull_request_id is required"))
+		return
+	}
+	if err := s.validateID("pull_request_id", prID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	seen := make(map[string]struct{}, len(oldUsers))
+	for i, id := range oldUsers {
+		id = strings.TrimSpace(id)
+		if err := s.validateID("old_user_ids", id); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		if _, dup := seen[id]; dup {
+			writeDecodeError(w, fmt.Errorf("old_user_ids contains %s twice", id))
+			return
+		}
+		seen[id] = struct{}{}
+		oldUsers[i] = id
+	}
+
+	pr, replacements, err := s.svc.ReassignReviewersWith(r.Context(), prID, oldUsers, opts)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pr":           expandPR(r, pr),
+		"replacements": replacements,
+	})
+}
+
+func (s *Server) prDeclineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID       string `json:"pull_request_id"`
+		ReviewerID string `json:"reviewer_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	req.ReviewerID = strings.TrimSpace(req.ReviewerID)
+	if req.PRID == "" || req.ReviewerID == "" {
+		writeDecodeError(w, errors.New("pull_request_id and reviewer_id are required"))
+		return
+	}
+	if err := s.validateID("pull_request_id", req.PRID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := s.validateID("reviewer_id", req.ReviewerID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	// declining is a reassignment initiated by the reviewer themselves
+	pr, replacedBy, err := s.svc.ReassignReviewer(r.Context(), req.PRID, req.ReviewerID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pr":                   expandPR(r, pr),
+		"replaced_by":          replacedBy,
+		"replaced_by_username": reviewerUsername(pr, replacedBy),
+	})
+}
+
+func (s *Server) prApproveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.PRID == "" || req.UserID == "" {
+		writeDecodeError(w, errors.New("pull_request_id and user_id are required"))
+		return
+	}
+
+	pr, err := s.svc.ApprovePullRequest(r.Context(), req.PRID, req.UserID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prRequestChangesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID   string `json:"pull_request_id"`
+		UserID string `json:"user_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	req.UserID = strings.TrimSpace(req.UserID)
+	if req.PRID == "" || req.UserID == "" {
+		writeDecodeError(w, errors.New("pull_request_id and user_id are required"))
+		return
+	}
+
+	pr, err := s.svc.RequestChanges(r.Context(), req.PRID, req.UserID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr)})
+}
+
+func (s *Server) prReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	var req struct {
+		PRID string `json:"pull_request_id"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	req.PRID = strings.TrimSpace(req.PRID)
+	if req.PRID == "" {
+		writeDecodeError(w, errors.New("pull_request_id is required"))
+		return
+	}
+	if err := s.validateID("pull_request_id", req.PRID); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	pr, replaced, err := s.svc.ReconcileReviewers(r.Context(), req.PRID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pr": expandPR(r, pr), "reassignments": replaced})
+}
+
+func (s *Server) prHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	prID := strings.TrimSpace(r.URL.Query().Get("pull_request_id"))
+	if prID == "" {
+		writeDecodeError(w, errors.New("pull_request_id query parameter is required"))
+		return
+	}
+
+	history, err := s.svc.ReassignmentHistory(r.Context(), prID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	timeline, err := s.svc.GetReviewerHistory(r.Context(), prID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pull_request_id":  prID,
+		"reassignments":    history,
+		"reviewer_history": timeline,
+	})
+}
+
+func (s *Server) prListHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	q := r.URL.Query()
+	filter := service.PRFilter{
+		Status:   strings.TrimSpace(q.Get("status")),
+		AuthorID: strings.TrimSpace(q.Get("author_id")),
+		TeamName: strings.TrimSpace(q.Get("team_name")),
+		Sort:     service.PRSort(strings.TrimSpace(q.Get("sort"))),
+	}
+	switch filter.Status {
+	case "", models.StatusOpen, models.StatusMerged, models.StatusClosed:
+	default:
+		writeDecodeError(w, errors.New("status must be one of OPEN, MERGED, CLOSED"))
+		return
+	}
+	switch filter.Sort {
+	case "", service.SortCreatedAt, service.SortMergedAt:
+	default:
+		writeDecodeError(w, errors.New("sort must be created_at or merged_at"))
+		return
+	}
+
+	var err error
+	filter.Limit, filter.Offset, err = parsePagination(r)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	prs, total, err := s.svc.ListPullRequests(r.Context(), filter)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pull_requests": prs,
+		"total":         total,
+		"limit":         filter.Limit,
+		"offset":        filter.Offset,
+	})
+}
+
+func (s *Server) userReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		writeDecodeError(w, errors.New("user_id query parameter is required"))
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if r.URL.Query().Has("cursor") {
+		if r.URL.Query().Has("offset") {
+			writeDecodeError(w, errors.New("cursor and offset are mutually exclusive"))
+			return
+		}
+		s.userReviewsByCursor(w, r, userID, limit)
+		return
+	}
+
+	prs, total, err := s.svc.ListUserReviews(r.Context(), userID, limit, offset)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user_id":       userID,
+		"pull_requests": prs,
+		"total":         total,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+func (s *Server) userReviewsByCursor(w http.ResponseWriter, r *http.Request, userID string, limit int) {
+	var cursor *service.ReviewCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		c, err := service.ParseReviewCursor(v)
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		cursor = &c
+	}
+
+	prs, next, err := s.svc.ListUserReviewsAfter(r.Context(), userID, cursor, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	resp := map[string]any{
+		"user_id":       userID,
+		"pull_requests": prs,
+		"limit":         limit,
+		"next_cursor":   nil,
+	}
+	if next != nil {
+		resp["next_cursor"] = next.String()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	from, err := parseTimeParam(r, "from")
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	to, err := parseTimeParam(r, "to")
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		writeDecodeError(w, errors.New("from must not be after to"))
+		return
+	}
+	q := service.StatsQuery{From: from, To: to, Sort: service.SortCountDesc}
+	switch sort := service.AssignmentSort(r.URL.Query().Get("sort")); sort {
+	case "":
+	case service.SortCountDesc, service.SortCountAsc, service.SortUsername:
+		q.Sort = sort
+	default:
+		writeDecodeError(w, errors.New("sort must be one of count_desc, count_asc, username"))
+		return
+	}
+	if r.URL.Query().Has("limit") || r.URL.Query().Has("offset") {
+		q.Limit, q.Offset, err = parsePagination(r)
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("min_count"); v != "" {
+		q.MinCount, err = strconv.Atoi(v)
+		if err != nil || q.MinCount < 0 {
+			writeDecodeError(w, errors.New("min_count must be a non-negative integer"))
+			return
+		}
+	}
+
+	var stats service.Stats
+	if teamName := strings.TrimSpace(r.URL.Query().Get("team_name")); teamName != "" {
+		stats, err = s.svc.StatsForTeam(r.Context(), teamName, q)
+	} else {
+		stats, err = s.svc.Stats(r.Context(), q)
+	}
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	if r.URL.Query().Get("envelope") == "true" {
+		writeJSON(w, http.StatusOK, map[string]any{"stats": stats})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	var afterID int64
+	if v := r.URL.Query().Get("after_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeDecodeError(w, errors.New("after_id must be a non-negative integer"))
+			return
+		}
+		afterID = n
+	}
+	limit, _, err := parsePagination(r)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	events, err := s.svc.ListEvents(r.Context(), afterID, limit)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+func reviewerUsername(pr models.PullRequest, userID string) string {
+	for _, m := range pr.ReviewersDetailed {
+		if m.UserID == userID {
+			return m.Username
+		}
+	}
+	return ""
+}
+
+func expandPR(r *http.Request, pr models.PullRequest) models.PullRequest {
+	if r.URL.Query().Get("expand") != "reviewers" {
+		pr.ReviewersDetailed = nil
+	}
+	return pr
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func (s *Server) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	q := r.URL.Query()
+	filter := service.AuditFilter{
+		Operation: strings.TrimSpace(q.Get("operation")),
+		EntityID:  strings.TrimSpace(q.Get("entity_id")),
+	}
+	var err error
+	filter.Limit, filter.Offset, err = parsePagination(r)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	entries, total, err := s.svc.GetAuditLog(r.Context(), filter)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
+
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageLimit, 0
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// teamLocation and prLocation are the GET URLs sent as Location for created
+// resources; ids are query-escaped since SetIDPattern may allow any rune.
+func teamLocation(teamName string) string {
+	return "/team/get?team_name=" + url.QueryEscape(teamName)
+}
+
+func prLocation(prID string) string {
+	return "/pullRequest/get?pull_request_id=" + url.QueryEscape(prID)
+}
+
+func (s *Server) validateID(field, value string) error {
+	if !s.idPattern.MatchString(value) {
+		return fmt.Errorf("%s %q must match %s", field, value, s.idPattern)
+	}
+	return nil
+}
+
+func (s *Server) sanitizeTeam(team models.Team) (models.Team, error) {
+	team.TeamName = strings.TrimSpace(team.TeamName)
+	if team.TeamName == "" {
+		return models.Team{}, errors.New("team_name is required")
+	}
+	if err := s.validateID("team_name", team.TeamName); err != nil {
+		return models.Team{}, err
+	}
+	if len(team.Members) == 0 {
+		return models.Team{}, errors.New("members must not be empty")
+	}
+	if team.DefaultReviewerCount < 0 {
+		return models.Team{}, errors.New("default_reviewer_count must not be negative")
+	}
+	for i, m := range team.Members {
+		m.UserID = strings.TrimSpace(m.UserID)
+		m.Username = strings.TrimSpace(m.Username)
+		if m.UserID == "" || m.Username == "" {
+			return models.Team{}, errors.New("member user_id and username are required")
+		}
+		if err := s.validateID("member user_id", m.UserID); err != nil {
+			return models.Team{}, err
+		}
+		if m.MaxOpenReviews != nil && *m.MaxOpenReviews < 0 {
+			return models.Team{}, errors.New("member max_open_reviews must not be negative")
+		}
+		if m.Role != nil {
+			if role := strings.TrimSpace(*m.Role); role != "" {
+				m.Role = &role
+			} else {
+				m.Role = nil
+			}
+		}
+		team.Members[i] = m
+	}
+	return team, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]any{
+		"error": map[string]any{
+			"code":    "METHOD_NOT_ALLOWED",
+			"message": "method not allowed, use " + strings.Join(allowed, " or "),
+		},
+	})
+}
+
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+			"error": map[string]any{
+				"code":    "PAYLOAD_TOO_LARGE",
+				"message": fmt.Sprintf("request body too large: limit is %d bytes", tooLarge.Limit),
+			},
+		})
+		return
+	}
+	if field, ok := unknownField(err); ok {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error": map[string]any{
+				"code":    "UNKNOWN_FIELD",
+				"message": fmt.Sprintf("unknown field %q", field),
+				"field":   field,
+			},
+		})
+		return
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"error": map[string]any{
+				"code":    "TYPE_MISMATCH",
+				"message": fmt.Sprintf("field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value),
+				"field":   typeErr.Field,
+			},
+		})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error": map[string]any{
+			"code":    "BAD_REQUEST",
+			"message": err.Error(),
+		},
+	})
+}
+
+// unknownField reports the field named by a DisallowUnknownFields error.
+// encoding/json has no exported type for it, only the message text, so this
+// depends on the stdlib wording; TestUnknownFieldMatchesDecoderError pins it.
+func unknownField(err error) (string, bool) {
+	field, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	return strings.Trim(field, `"`), true
+}
+
+func writeAppError(w http.ResponseWriter, err error) {
+	status, payload := appErrorPayload(err)
+	writeJSON(w, status, map[string]any{"error": payload})
+}
+
+// appErrorPayload maps a service error to its HTTP status and the body of the
+// "error" envelope.
+func appErrorPayload(err error) (int, map[string]any) {
+	var appErr *service.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Status, map[string]any{
+			"code":    appErr.Code,
+			"message": appErr.Message,
+		}
+	}
+	if service.IsTimeout(err) {
+		return http.StatusServiceUnavailable, map[string]any{
+			"code":    "TIMEOUT",
+			"message": "operation timed out",
+		}
+	}
+	return http.StatusInternalServerError, map[string]any{
+		"code":    "INTERNAL",
+		"message": err.Error(),
+	}
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+func newTestServer(t *testing.T) (*Server, *service.Service) {
+	t.Helper()
+	svc := service.NewWithRand(dbtest.Open(t), rand.New(rand.NewSource(1)))
+	return New(svc), svc
+}
+
+// offlineDB is a pool whose every query fails to connect.
+func offlineDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("open offline db: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newOfflineServer returns a server whose database is unreachable, for tests
+// of requests that are answered before the service touches it.
+func newOfflineServer(t *testing.T) *Server {
+	t.Helper()
+	return New(service.New(offlineDB(t)))
+}
+
+func do(t *testing.T, h http.Handler, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+}
+
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Field   string `json:"field"`
+	} `json:"error"`
+}
+
+func assertError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) errorBody {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, status, rec.Body.String())
+	}
+	var body errorBody
+	decodeBody(t, rec, &body)
+	if body.Error.Code != code {
+		t.Fatalf("code = %q, want %q; body %s", body.Error.Code, code, rec.Body.String())
+	}
+	return body
+}
+
+func assertStatus(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, status, rec.Body.String())
+	}
+}
+
+type teamMember struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive bool   `json:"is_active"`
+}
+
+func mustAddTeam(t *testing.T, h http.Handler, name string, activeIDs ...string) {
+	t.Helper()
+	members := make([]teamMember, 0, len(activeIDs))
+	for _, id := range activeIDs {
+		members = append(members, teamMember{UserID: id, Username: "user-" + id, IsActive: true})
+	}
+	rec := do(t, h, http.MethodPost, "/team/add", map[string]any{"team_name": name, "members": members})
+	assertStatus(t, rec, http.StatusCreated)
+}
+
+type prResponse struct {
+	PR struct {
+		ID                string   `json:"pull_request_id"`
+		AuthorID          string   `json:"author_id"`
+		AuthorUsername    string   `json:"author_username"`
+		Status            string   `json:"status"`
+		AssignedReviewers []string `json:"assigned_reviewers"`
+		Approvals         []string `json:"approvals"`
+	} `json:"pr"`
+}
+
+func TestCreatePRReviewerCountField(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  int
+	}{
+		{name: "one", count: 1, want: 1},
+		{name: "more than candidates", count: 3, want: 2},
+		{name: "default", count: 0, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newTestServer(t)
+			h := srv.Handler()
+			mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+
+			body := map[string]any{"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1"}
+			if tt.count > 0 {
+				body["reviewer_count"] = tt.count
+			}
+			rec := do(t, h, http.MethodPost, "/pullRequest/create", body)
+			assertStatus(t, rec, http.StatusCreated)
+			var resp prResponse
+			decodeBody(t, rec, &resp)
+			if len(resp.PR.AssignedReviewers) != tt.want {
+				t.Fatalf("got reviewers %v, want %d", resp.PR.AssignedReviewers, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePRMinReviewersAboveTeamDefault(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3", "u4")
+
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1", "min_reviewers": 3,
+	})
+	assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+
+	rec = do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "Add search", "author_id": "u1", "min_reviewers": 3, "reviewer_count": 3,
+	})
+	assertStatus(t, rec, http.StatusCreated)
+}
@@ -0,0 +1,82 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStatsHandlerValidatesRange(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, target := range []string{
+		"/stats?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+		"/stats?from=yesterday",
+		"/stats?to=2025-01-01",
+	} {
+		rec := do(t, h, http.MethodGet, target, nil)
+		assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+func TestStatsHandlerValidatesPaging(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, target := range []string{
+		"/stats?sort=name",
+		"/stats?sort=COUNT_DESC",
+		"/stats?limit=0",
+		"/stats?limit=-1",
+		"/stats?limit=1000000",
+		"/stats?limit=ten",
+		"/stats?offset=-1",
+	} {
+		assertError(t, do(t, h, http.MethodGet, target, nil), http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+func TestStatsHandlerValidatesFilters(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, target := range []string{
+		"/stats?min_count=-1",
+		"/stats?min_count=some",
+		"/stats?min_count=1.5",
+	} {
+		assertError(t, do(t, h, http.MethodGet, target, nil), http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+func TestStatsEnvelope(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2")
+
+	plain := do(t, h, http.MethodGet, "/stats", nil)
+	assertStatus(t, plain, http.StatusOK)
+	var top map[string]json.RawMessage
+	decodeBody(t, plain, &top)
+	if _, ok := top["total_prs"]; !ok {
+		t.Fatalf("default /stats = %s, want fields at the top level", plain.Body.String())
+	}
+	if _, ok := top["stats"]; ok {
+		t.Fatalf("default /stats is wrapped: %s", plain.Body.String())
+	}
+
+	for _, v := range []string{"false", "1"} {
+		rec := do(t, h, http.MethodGet, "/stats?envelope="+v, nil)
+		assertStatus(t, rec, http.StatusOK)
+		if rec.Body.String() != plain.Body.String() {
+			t.Fatalf("envelope=%s changed the body: %s", v, rec.Body.String())
+		}
+	}
+
+	wrapped := do(t, h, http.MethodGet, "/stats?envelope=true", nil)
+	assertStatus(t, wrapped, http.StatusOK)
+	var env map[string]json.RawMessage
+	decodeBody(t, wrapped, &env)
+	if len(env) != 1 || env["stats"] == nil {
+		t.Fatalf("envelope=true body = %s, want only a stats key", wrapped.Body.String())
+	}
+	if strings.TrimSpace(string(env["stats"])) != strings.TrimSpace(plain.Body.String()) {
+		t.Fatalf("wrapped stats %s differ from %s", env["stats"], plain.Body.String())
+	}
+}
@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTeamGetActiveOnly(t *testing.T) {
+	srv, _ := newTestServer(t)
+	h := srv.Handler()
+	rec := do(t, h, http.MethodPost, "/team/add", map[string]any{"team_name": "backend", "members": []teamMember{
+		{UserID: "u1", Username: "a", IsActive: true},
+		{UserID: "u2", Username: "b", IsActive: false},
+		{UserID: "u3", Username: "c", IsActive: true},
+	}})
+	assertStatus(t, rec, http.StatusCreated)
+
+	for target, want := range map[string]struct{ members, memberCount int }{
+		"/team/get?team_name=backend":                   {members: 3, memberCount: 3},
+		"/team/get?team_name=backend&active_only=false": {members: 3, memberCount: 3},
+		"/team/get?team_name=backend&active_only=true":  {members: 2, memberCount: 2},
+	} {
+		rec := do(t, h, http.MethodGet, target, nil)
+		assertStatus(t, rec, http.StatusOK)
+		var body struct {
+			Members     []teamMember `json:"members"`
+			MemberCount int          `json:"member_count"`
+			ActiveCount int          `json:"active_count"`
+			TotalCount  int          `json:"total_count"`
+		}
+		decodeBody(t, rec, &body)
+		if len(body.Members) != want.members || body.MemberCount != want.memberCount {
+			t.Fatalf("%s: %d members, member_count %d; want %d and %d",
+				target, len(body.Members), body.MemberCount, want.members, want.memberCount)
+		}
+		if body.ActiveCount != 2 || body.TotalCount != 3 {
+			t.Fatalf("%s: active_count %d, total_count %d; want 2 and 3", target, body.ActiveCount, body.TotalCount)
+		}
+		for _, m := range body.Members {
+			if want.members == 2 && !m.IsActive {
+				t.Fatalf("%s: inactive member %s returned", target, m.UserID)
+			}
+		}
+	}
+}
+
+func TestTeamUpdateMemberValidation(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, body := range []string{
+		`{"user_id":"u1","username":"a"}`,
+		`{"team_name":"backend","username":"a"}`,
+		`{"team_name":"backend","user_id":"u1"}`,
+		`{"team_name":"backend","user_id":"u1","username":"  "}`,
+	} {
+		assertError(t, do(t, h, http.MethodPost, "/team/updateMember", body), http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
+
+func TestTeamReviewerCountValidation(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	for _, body := range []string{
+		`{"reviewer_count":1}`,
+		`{"team_name":"  ","reviewer_count":1}`,
+		`{"team_name":"backend"}`,
+		`{"team_name":"backend","reviewer_count":-1}`,
+	} {
+		assertError(t, do(t, h, http.MethodPost, "/team/setReviewerCount", body), http.StatusBadRequest, "BAD_REQUEST")
+	}
+	body := `{"team_name":"backend","default_reviewer_count":-1,"members":[{"user_id":"u1","username":"a","is_active":true}]}`
+	assertError(t, do(t, h, http.MethodPost, "/team/add", body), http.StatusBadRequest, "BAD_REQUEST")
+}
@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+	"github.com/lib/pq"
+)
+
+func TestAppErrorPayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "app error", err: &service.AppError{Status: 404, Code: service.CodeNotFound, Message: "team not found"}, status: 404, code: service.CodeNotFound},
+		{name: "deadline", err: fmt.Errorf("insert pr: %w", context.DeadlineExceeded), status: 503, code: "TIMEOUT"},
+		{name: "query canceled", err: fmt.Errorf("insert pr: %w", &pq.Error{Code: "57014", Message: "canceling statement due to user request"}), status: 503, code: "TIMEOUT"},
+		{name: "other pq error", err: &pq.Error{Code: "23505"}, status: 500, code: "INTERNAL"},
+		{name: "other", err: errors.New("boom"), status: 500, code: "INTERNAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, payload := appErrorPayload(tt.err)
+			if status != tt.status || payload["code"] != tt.code {
+				t.Fatalf("appErrorPayload = %d %v, want %d %s", status, payload, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestSlowQueryReturnsTimeout(t *testing.T) {
+	conn := dbtest.Open(t)
+	svc := service.NewWithRand(conn, rand.New(rand.NewSource(1)))
+	svc.SetOpTimeout(200 * time.Millisecond)
+	h := New(svc).Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3")
+	if _, err := conn.Exec(`CREATE FUNCTION slow_insert() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_sleep(5);
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`); err != nil {
+		t.Fatalf("create function: %v", err)
+	}
+	if _, err := conn.Exec(`CREATE TRIGGER slow_insert BEFORE INSERT ON pull_requests FOR EACH ROW EXECUTE FUNCTION slow_insert()`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	start := time.Now()
+	rec := do(t, h, http.MethodPost, "/pullRequest/create", map[string]any{
+		"pull_request_id": "pr-1", "pull_request_name": "slow", "author_id": "u1",
+	})
+	assertError(t, rec, http.StatusServiceUnavailable, "TIMEOUT")
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("request took %s, the statement was not canceled", elapsed)
+	}
+
+	if _, err := conn.Exec(`DROP TRIGGER slow_insert ON pull_requests`); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+	assertStatus(t, do(t, h, http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil), http.StatusNotFound)
+}
@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+	"github.com/123jjck/avito-trainee-assignment/internal/service"
+)
+
+type setActiveResponse struct {
+	User struct {
+		UserID   string `json:"user_id"`
+		IsActive bool   `json:"is_active"`
+	} `json:"user"`
+	Reassignment *struct {
+		Reassigned []service.ReassignResult  `json:"reassigned"`
+		Failed     []service.ReassignFailure `json:"failed"`
+	} `json:"reassignment"`
+	ReassignmentError *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"reassignment_error"`
+}
+
+// newOffboardingServer returns a server where u2 reviews pr-1 and pr-2 and
+// u5 is the only member free to replace them.
+func newOffboardingServer(t *testing.T) (http.Handler, *service.Service, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+	conn := dbtest.Open(t)
+	svc := service.NewWithRand(conn, rand.New(rand.NewSource(1)))
+	h := New(svc).Handler()
+	mustAddTeam(t, h, "backend", "u1", "u2", "u3", "u4")
+	for _, id := range []string{"pr-1", "pr-2"} {
+		if _, err := svc.CreatePullRequest(ctx, service.CreatePRInput{ID: id, Name: id, Author: "u1", ReviewerCount: 3}); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	if _, err := svc.AddTeamMembers(ctx, "backend", []models.TeamMember{{UserID: "u5", Username: "user-u5", IsActive: true}}); err != nil {
+		t.Fatalf("add member: %v", err)
+	}
+	return h, svc, conn
+}
+
+func TestSetIsActiveWithoutReassign(t *testing.T) {
+	h, svc, _ := newOffboardingServer(t)
+
+	rec := do(t, h, http.MethodPost, "/users/setIsActive", map[string]any{"user_id": "u2", "is_active": false})
+	assertStatus(t, rec, http.StatusOK)
+	var resp setActiveResponse
+	decodeBody(t, rec, &resp)
+	if resp.User.IsActive || resp.Reassignment != nil || resp.ReassignmentError != nil {
+		t.Fatalf("response = %+v, want just the deactivated user", resp)
+	}
+	pr, err := svc.GetPullRequest(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("get PR: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 3 {
+		t.Fatalf("reviewers = %v, deactivation alone must not reassign", pr.AssignedReviewers)
+	}
+}
+
+func TestSetIsActiveReassignsOpenReviews(t *testing.T) {
+	h, _, _ := newOffboardingServer(t)
+
+	rec := do(t, h, http.MethodPost, "/users/setIsActive", map[string]any{"user_id": "u2", "is_active": false, "reassign_open": true})
+	assertStatus(t, rec, http.StatusOK)
+	var resp setActiveResponse
+	decodeBody(t, rec, &resp)
+	if resp.User.IsActive || resp.Reassignment == nil || resp.ReassignmentError != nil {
+		t.Fatalf("response = %+v", resp)
+	}
+	want := []service.ReassignResult{{PullRequestID: "pr-1", ReplacedBy: "u5"}, {PullRequestID: "pr-2", ReplacedBy: "u5"}}
+	if len(resp.Reassignment.Reassigned) != 2 || resp.Reassignment.Reassigned[0] != want[0] || resp.Reassignment.Reassigned[1] != want[1] {
+		t.Fatalf("reassigned = %+v, want %+v", resp.Reassignment.Reassigned, want)
+	}
+}
+
+func TestSetIsActiveReportsPartialReassignment(t *testing.T) {
+	h, _, conn := newOffboardingServer(t)
+	if _, err := conn.Exec(`CREATE FUNCTION fail_pr2() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.pull_request_id = 'pr-2' THEN
+				RAISE EXCEPTION 'simulated failure';
+			END IF;
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`); err != nil {
+		t.Fatalf("create function: %v", err)
+	}
+	if _, err := conn.Exec(`CREATE TRIGGER fail_pr2 BEFORE INSERT ON reassignment_log FOR EACH ROW EXECUTE FUNCTION fail_pr2()`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	rec := do(t, h, http.MethodPost, "/users/setIsActive", map[string]any{"user_id": "u2", "is_active": false, "reassign_open": true})
+	// the deactivation is committed, so this is not reported as a 500
+	assertStatus(t, rec, http.StatusOK)
+	var resp setActiveResponse
+	decodeBody(t, rec, &resp)
+	if resp.User.IsActive || resp.Reassignment == nil || resp.ReassignmentError == nil {
+		t.Fatalf("response = %+v, want the user, a partial summary and an error", resp)
+	}
+	if len(resp.Reassignment.Reassigned) != 1 || resp.Reassignment.Reassigned[0].PullRequestID != "pr-1" {
+		t.Fatalf("reassigned = %+v, want only pr-1", resp.Reassignment.Reassigned)
+	}
+	if resp.ReassignmentError.Code != "INTERNAL" {
+		t.Fatalf("reassignment_error = %+v", resp.ReassignmentError)
+	}
+
+	var active bool
+	if err := conn.QueryRow(`SELECT is_active FROM users WHERE user_id = 'u2'`).Scan(&active); err != nil {
+		t.Fatalf("load user: %v", err)
+	}
+	if active {
+		t.Fatalf("u2 is active again, the deactivation was lost")
+	}
+}
@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{value: "u1", ok: true},
+		{value: "pr-1001", ok: true},
+		{value: "team_backend.v2", ok: true},
+		{value: strings.Repeat("a", 64), ok: true},
+		{value: "", ok: false},
+		{value: strings.Repeat("a", 65), ok: false},
+		{value: "with space", ok: false},
+		{value: "emoji🙂", ok: false},
+		{value: "slash/id", ok: false},
+		{value: "кириллица", ok: false},
+	}
+	s := newOfflineServer(t)
+	for _, tt := range tests {
+		err := s.validateID("user_id", tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("validateID(%q) = %v, want ok=%v", tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestSetIDPattern(t *testing.T) {
+	s := newOfflineServer(t)
+	if err := s.SetIDPattern(`^[0-9]+$`); err != nil {
+		t.Fatalf("set pattern: %v", err)
+	}
+	if err := s.validateID("user_id", "123"); err != nil {
+		t.Fatalf("validateID(123) = %v", err)
+	}
+	if err := s.validateID("user_id", "u1"); err == nil {
+		t.Fatalf("validateID(u1) passed a digits-only pattern")
+	}
+	if err := s.SetIDPattern(`([`); err == nil {
+		t.Fatalf("invalid pattern accepted")
+	}
+}
+
+func TestHandlersRejectInvalidIDs(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+	tests := []struct {
+		path string
+		body string
+	}{
+		{path: "/team/add", body: `{"team_name":"back end","members":[{"user_id":"u1","username":"a","is_active":true}]}`},
+		{path: "/pullRequest/create", body: `{"pull_request_id":"pr 1","pull_request_name":"x","author_id":"u1"}`},
+		{path: "/pullRequest/create", body: `{"pull_request_id":"pr-1","pull_request_name":"x","author_id":"u1🙂"}`},
+		{path: "/users/setIsActive", body: `{"user_id":"u 1","is_active":false}`},
+		{path: "/pullRequest/reassign", body: `{"pull_request_id":"pr-1","old_user_id":"u/1"}`},
+	}
+	for _, tt := range tests {
+		rec := do(t, h, http.MethodPost, tt.path, tt.body)
+		assertError(t, rec, http.StatusBadRequest, "BAD_REQUEST")
+	}
+}
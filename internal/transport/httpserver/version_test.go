@@ -0,0 +1,34 @@
+package httpserver
+
+import (
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/buildinfo"
+)
+
+func TestVersionHandler(t *testing.T) {
+	h := newOfflineServer(t).Handler()
+
+	rec := do(t, h, http.MethodGet, "/version", nil)
+	assertStatus(t, rec, http.StatusOK)
+	var body map[string]string
+	decodeBody(t, rec, &body)
+	want := map[string]string{"version": "dev", "commit": "unknown", "build_time": "unknown", "go_version": runtime.Version()}
+	if len(body) != len(want) {
+		t.Fatalf("body = %v, want fields %v", body, want)
+	}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %q, want %q", k, body[k], v)
+		}
+	}
+
+	defer func(v string) { buildinfo.Version = v }(buildinfo.Version)
+	buildinfo.Version = "v1.4.0"
+	decodeBody(t, do(t, h, http.MethodGet, "/version", nil), &body)
+	if body["version"] != "v1.4.0" {
+		t.Fatalf("version = %q after linking v1.4.0", body["version"])
+	}
+}
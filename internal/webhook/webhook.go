@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+const (
+	defaultPollInterval = 2 * time.Second
+	defaultBatchSize    = 50
+	minBackoff          = time.Second
+	maxBackoff          = time.Minute
+)
+
+type Dispatcher struct {
+	db           *sql.DB
+	url          string
+	client       *http.Client
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func New(db *sql.DB, url string) *Dispatcher {
+	return &Dispatcher{
+		db:           db,
+		url:          url,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: defaultPollInterval,
+		batchSize:    defaultBatchSize,
+	}
+}
+
+// Run delivers undelivered outbox events in id order until ctx is cancelled.
+// A failed delivery stops the batch so events are never sent out of order;
+// the next attempt waits with exponential backoff.
+func (d *Dispatcher) Run(ctx context.Context) {
+	backoff := time.Duration(0)
+	for {
+		wait := d.pollInterval
+		if err := d.deliverPending(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			wait = backoff
+			log.Printf("webhook delivery failed, retrying in %s: %v", wait, err)
+		} else {
+			backoff = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (d *Dispatcher) deliverPending(ctx context.Context) error {
+	events, err := d.pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err := d.send(ctx, e); err != nil {
+			return fmt.Errorf("event %d: %w", e.ID, err)
+		}
+		if _, err := d.db.ExecContext(ctx,
+			`UPDATE events SET delivered_at = now() WHERE id = $1`, e.ID,
+		); err != nil {
+			return fmt.Errorf("mark event %d delivered: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) pending(ctx context.Context) ([]models.Event, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT id, event_type, payload, created_at
+		 FROM events
+		 WHERE delivered_at IS NULL
+		 ORDER BY id
+		 LIMIT $1`,
+		d.batchSize,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load pending events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (d *Dispatcher) send(ctx context.Context, e models.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func nextBackoff(current time.Duration) time.Duration {
+	if current < minBackoff {
+		return minBackoff
+	}
+	return min(current*2, maxBackoff)
+}
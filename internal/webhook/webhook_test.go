@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/123jjck/avito-trainee-assignment/internal/dbtest"
+	"github.com/123jjck/avito-trainee-assignment/internal/models"
+)
+
+// receiver answers with the queued statuses, then 200, and records every
+// event it accepted.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	attempts int
+	accepted []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	var e models.Event
+	if err := json.NewDecoder(req.Body).Decode(&e); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.accepted = append(r.accepted, e.Type)
+}
+
+func undelivered(t *testing.T, d *Dispatcher) int {
+	t.Helper()
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM events WHERE delivered_at IS NULL`).Scan(&n); err != nil {
+		t.Fatalf("count undelivered events: %v", err)
+	}
+	return n
+}
+
+func TestDeliverPendingRetriesOn500(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.Open(t)
+	for _, typ := range []string{"pr.created", "pr.merged"} {
+		if _, err := conn.Exec(`INSERT INTO events (event_type, payload) VALUES ($1, '{}')`, typ); err != nil {
+			t.Fatalf("insert event: %v", err)
+		}
+	}
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError}}
+	ts := httptest.NewServer(rcv)
+	defer ts.Close()
+	d := New(conn, ts.URL)
+
+	if err := d.deliverPending(ctx); err == nil {
+		t.Fatalf("delivery succeeded although the receiver answered 500")
+	}
+	if n := undelivered(t, d); n != 2 {
+		t.Fatalf("undelivered = %d after a failed attempt, want 2", n)
+	}
+
+	if err := d.deliverPending(ctx); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if n := undelivered(t, d); n != 0 {
+		t.Fatalf("undelivered = %d after the retry, want 0", n)
+	}
+	if rcv.attempts != 3 || len(rcv.accepted) != 2 || rcv.accepted[0] != "pr.created" || rcv.accepted[1] != "pr.merged" {
+		t.Fatalf("attempts = %d, accepted = %v", rcv.attempts, rcv.accepted)
+	}
+
+	// delivered rows are not sent again
+	if err := d.deliverPending(ctx); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if rcv.attempts != 3 {
+		t.Fatalf("attempts = %d, delivered events were resent", rcv.attempts)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	steps := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	var b time.Duration
+	for _, want := range steps {
+		if b = nextBackoff(b); b != want {
+			t.Fatalf("backoff = %s, want %s", b, want)
+		}
+	}
+	if got := nextBackoff(maxBackoff); got != maxBackoff {
+		t.Fatalf("backoff = %s, want the %s ceiling", got, maxBackoff)
+	}
+}
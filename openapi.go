@@ -0,0 +1,8 @@
+// Package assignment exposes files from the repository root that are needed
+// at runtime.
+package assignment
+
+import _ "embed"
+
+//go:embed openapi.yml
+var OpenAPISpec []byte